@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"clone-spotter/internal/core"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// fileName is the name of the cache database inside the cache directory
+const fileName = "hashes.db"
+
+// flushThreshold is the number of pending entries buffered before they are
+// written to disk in a single transaction
+const flushThreshold = 1000
+
+// hashesBucket holds one entry per algorithm and path
+var hashesBucket = []byte("hashes")
+
+// entry is the value stored for each cached file
+type entry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Inode   uint64 `json:"inode"`
+	Hash    string `json:"hash"`
+}
+
+// Stats describes the contents of the cache
+type Stats struct {
+	Path        string
+	Entries     int
+	SizeOnDisk  int64
+	ByAlgorithm map[core.HashAlgorithm]int
+}
+
+// Store is a persistent hash cache backed by a single-file embedded database.
+// It implements core.HashCache.
+type Store struct {
+	db      *bolt.DB
+	path    string
+	pending map[string]entry
+	mu      sync.Mutex
+}
+
+// DefaultPath returns the location of the cache under the user cache directory
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "clone-spotter", fileName), nil
+}
+
+// Open opens the cache at the given path, creating it if necessary
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Fail fast instead of blocking when another scan holds the cache
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(hashesBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize cache %s: %w", path, err)
+	}
+
+	return &Store{
+		db:      db,
+		path:    path,
+		pending: make(map[string]entry),
+	}, nil
+}
+
+// OpenDefault opens the cache at DefaultPath
+func OpenDefault() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+// entryKey builds the database key for a path hashed with an algorithm
+func entryKey(algorithm core.HashAlgorithm, path string) string {
+	return string(algorithm) + "\x00" + path
+}
+
+// splitKey reverses entryKey
+func splitKey(key []byte) (core.HashAlgorithm, string, bool) {
+	for i, b := range key {
+		if b == 0 {
+			return core.HashAlgorithm(key[:i]), string(key[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// matches reports whether a stored entry is still valid for the given key
+func (e entry) matches(key core.CacheKey) bool {
+	return e.Size == key.Size && e.ModTime == key.ModTime && e.Inode == key.Inode
+}
+
+// Lookup returns the cached hash for a file if path, size, mtime, inode and
+// algorithm all match the stored entry
+func (s *Store) Lookup(key core.CacheKey) (string, bool) {
+	k := entryKey(key.Algorithm, key.Path)
+
+	s.mu.Lock()
+	if e, ok := s.pending[k]; ok {
+		s.mu.Unlock()
+		if !e.matches(key) {
+			return "", false
+		}
+		return e.Hash, true
+	}
+	s.mu.Unlock()
+
+	var e entry
+	found := false
+	s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(hashesBucket).Get([]byte(k))
+		if data != nil && json.Unmarshal(data, &e) == nil {
+			found = e.matches(key)
+		}
+		return nil
+	})
+
+	if !found {
+		return "", false
+	}
+	return e.Hash, true
+}
+
+// Store records the hash for a file. Writes are buffered and flushed in
+// batches; call Close to persist any remaining entries.
+func (s *Store) Store(key core.CacheKey, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[entryKey(key.Algorithm, key.Path)] = entry{
+		Size:    key.Size,
+		ModTime: key.ModTime,
+		Inode:   key.Inode,
+		Hash:    hash,
+	}
+
+	if len(s.pending) >= flushThreshold {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// flushLocked writes all pending entries in one transaction. The caller must
+// hold s.mu.
+func (s *Store) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(hashesBucket)
+		for k, e := range s.pending {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(k), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	s.pending = make(map[string]entry)
+	return nil
+}
+
+// Stats returns the number of entries in the cache, broken down by algorithm
+func (s *Store) Stats() (Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flushLocked(); err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		Path:        s.path,
+		ByAlgorithm: make(map[core.HashAlgorithm]int),
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		stats.SizeOnDisk = tx.Size()
+		return tx.Bucket(hashesBucket).ForEach(func(k, v []byte) error {
+			stats.Entries++
+			if algorithm, _, ok := splitKey(k); ok {
+				stats.ByAlgorithm[algorithm]++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Prune removes entries for files that no longer exist or have changed since
+// they were hashed, and returns how many entries were removed
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flushLocked(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(hashesBucket)
+		var stale [][]byte
+
+		err := bucket.ForEach(func(k, v []byte) error {
+			algorithm, path, ok := splitKey(k)
+			var e entry
+			if !ok || json.Unmarshal(v, &e) != nil {
+				stale = append(stale, append([]byte(nil), k...))
+				return nil
+			}
+
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() || !e.matches(core.NewCacheKey(path, info, algorithm)) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune cache: %w", err)
+	}
+
+	return removed, nil
+}
+
+// Clear removes every entry from the cache
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = make(map[string]entry)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(hashesBucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(hashesBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes pending entries and closes the database
+func (s *Store) Close() error {
+	s.mu.Lock()
+	flushErr := s.flushLocked()
+	s.mu.Unlock()
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close cache: %w", err)
+	}
+	return flushErr
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"clone-spotter/internal/core"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), fileName)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+// writeFile writes content to a new file under dir and returns its cache key
+func writeFile(t *testing.T, dir, name, content string) core.CacheKey {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core.NewCacheKey(path, info, core.MD5)
+}
+
+func TestLookup(t *testing.T) {
+	s, _ := openTestStore(t)
+	key := core.CacheKey{Path: "/data/a", Size: 10, ModTime: 1000, Inode: 42, Algorithm: core.MD5}
+	if err := s.Store(key, "digest"); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := func(change func(k *core.CacheKey)) core.CacheKey {
+		k := key
+		change(&k)
+		return k
+	}
+	tests := []struct {
+		name string
+		key  core.CacheKey
+		hit  bool
+	}{
+		{"same file", key, true},
+		{"size changed", changed(func(k *core.CacheKey) { k.Size++ }), false},
+		{"mtime changed", changed(func(k *core.CacheKey) { k.ModTime++ }), false},
+		{"inode changed", changed(func(k *core.CacheKey) { k.Inode++ }), false},
+		{"other path", changed(func(k *core.CacheKey) { k.Path = "/data/b" }), false},
+		{"other algorithm", changed(func(k *core.CacheKey) { k.Algorithm = core.SHA256 }), false},
+	}
+	// Check the buffered entry, then the same entry once written to disk
+	for _, stage := range []string{"pending", "flushed"} {
+		if stage == "flushed" {
+			s.mu.Lock()
+			err := s.flushLocked()
+			s.mu.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tt := range tests {
+			hash, ok := s.Lookup(tt.key)
+			if ok != tt.hit || ok && hash != "digest" {
+				t.Errorf("%s, %s: Lookup = %q, %v, want hit %v", stage, tt.name, hash, ok, tt.hit)
+			}
+		}
+	}
+}
+
+func TestStoreBuffersUntilThreshold(t *testing.T) {
+	s, path := openTestStore(t)
+	for i := 0; i < flushThreshold-1; i++ {
+		key := core.CacheKey{Path: "/data/" + strconv.Itoa(i), Algorithm: core.MD5}
+		if err := s.Store(key, "digest"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.pending) != flushThreshold-1 {
+		t.Errorf("%d entries pending, want %d", len(s.pending), flushThreshold-1)
+	}
+
+	if err := s.Store(core.CacheKey{Path: "/data/last", Algorithm: core.MD5}, "digest"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("%d entries pending after reaching the threshold, want 0", len(s.pending))
+	}
+
+	// Entries stored after the last flush are written by Close
+	late := core.CacheKey{Path: "/data/late", Algorithm: core.MD5}
+	if err := s.Store(late, "late digest"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if hash, ok := reopened.Lookup(late); !ok || hash != "late digest" {
+		t.Errorf("Lookup after reopening = %q, %v, want the entry stored before Close", hash, ok)
+	}
+	stats, err := reopened.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != flushThreshold+1 || stats.ByAlgorithm[core.MD5] != flushThreshold+1 {
+		t.Errorf("Stats = %+v, want %d MD5 entries", stats, flushThreshold+1)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s, _ := openTestStore(t)
+	dir := t.TempDir()
+	kept := writeFile(t, dir, "kept", "unchanged")
+	deleted := writeFile(t, dir, "deleted", "removed later")
+	changed := writeFile(t, dir, "changed", "before")
+	for _, key := range []core.CacheKey{kept, deleted, changed} {
+		if err := s.Store(key, "digest"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Remove(deleted.Path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(changed.Path, []byte("after the change"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Prune removed %d entries, want 2", removed)
+	}
+	if _, ok := s.Lookup(kept); !ok {
+		t.Error("entry of an unchanged file was pruned")
+	}
+	for _, key := range []core.CacheKey{deleted, changed} {
+		if _, ok := s.Lookup(key); ok {
+			t.Errorf("entry of %s survived pruning", key.Path)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	s, _ := openTestStore(t)
+	flushed := core.CacheKey{Path: "/data/flushed", Algorithm: core.MD5}
+	pending := core.CacheKey{Path: "/data/pending", Algorithm: core.MD5}
+	if err := s.Store(flushed, "digest"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stats(); err != nil { // Flushes the first entry
+		t.Fatal(err)
+	}
+	if err := s.Store(pending, "digest"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []core.CacheKey{flushed, pending} {
+		if _, ok := s.Lookup(key); ok {
+			t.Errorf("entry of %s survived Clear", key.Path)
+		}
+	}
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("%d entries after Clear, want 0", stats.Entries)
+	}
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"clone-spotter/internal/core"
+	"clone-spotter/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchSizeMB int
+	benchRounds int
+)
+
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Compare hashing throughput of the supported algorithms",
+	Long: `Hash an in-memory buffer with every supported algorithm and report the
+throughput of each, to help pick an algorithm for your hardware.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBenchmark()
+	},
+}
+
+func init() {
+	benchmarkCmd.Flags().IntVar(&benchSizeMB, "size", 64, "Buffer size in MB")
+	benchmarkCmd.Flags().IntVar(&benchRounds, "rounds", 3, "Number of times each algorithm hashes the buffer")
+}
+
+func runBenchmark() error {
+	if benchSizeMB <= 0 {
+		return fmt.Errorf("buffer size must be positive: %d", benchSizeMB)
+	}
+	if benchRounds < 1 {
+		return fmt.Errorf("rounds must be at least 1: %d", benchRounds)
+	}
+
+	data := make([]byte, benchSizeMB*1024*1024)
+	if _, err := rand.Read(data); err != nil {
+		return fmt.Errorf("failed to generate benchmark data: %w", err)
+	}
+
+	utils.LogBold(fmt.Sprintf("\n⏱️  %s Hash Benchmark", AppName))
+	utils.LogCyan(strings.Repeat("=", 50))
+	utils.LogInfo(fmt.Sprintf("Hashing %d MB x %d rounds per algorithm", benchSizeMB, benchRounds))
+	fmt.Println()
+
+	results := core.BenchmarkAlgorithms(data, benchRounds)
+
+	var baseline float64
+	for _, result := range results {
+		if result.Algorithm == core.MD5 {
+			baseline = result.Throughput()
+		}
+	}
+
+	for _, result := range results {
+		relative := ""
+		if baseline > 0 {
+			relative = fmt.Sprintf("%.2fx md5", result.Throughput()/baseline)
+		}
+		fmt.Printf("  %-10s %12s/s  %s\n", result.Algorithm,
+			utils.FormatFileSize(int64(result.Throughput())), relative)
+	}
+
+	return nil
+}
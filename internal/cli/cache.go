@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"clone-spotter/internal/cache"
+	"clone-spotter/internal/core"
+	"clone-spotter/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the persistent hash cache",
+	Long: `Inspect and maintain the on-disk cache of file hashes. Scans reuse a cached
+hash when a file's path, size, modification time, inode and algorithm all match.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache location and entry counts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withCache(func(store *cache.Store) error {
+			stats, err := store.Stats()
+			if err != nil {
+				return err
+			}
+
+			utils.LogInfo(fmt.Sprintf("Cache: %s", stats.Path))
+			utils.LogInfo(fmt.Sprintf("Size on disk: %s", utils.FormatFileSize(stats.SizeOnDisk)))
+			utils.LogInfo(fmt.Sprintf("Entries: %d", stats.Entries))
+
+			algorithms := make([]string, 0, len(stats.ByAlgorithm))
+			for algo := range stats.ByAlgorithm {
+				algorithms = append(algorithms, string(algo))
+			}
+			sort.Strings(algorithms)
+			for _, algo := range algorithms {
+				fmt.Printf("  %-10s %d\n", algo, stats.ByAlgorithm[core.HashAlgorithm(algo)])
+			}
+			return nil
+		})
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove entries for files that were deleted or changed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withCache(func(store *cache.Store) error {
+			removed, err := store.Prune()
+			if err != nil {
+				return err
+			}
+			utils.LogSuccess(fmt.Sprintf("Pruned %d stale cache entries", removed))
+			return nil
+		})
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every entry from the cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withCache(func(store *cache.Store) error {
+			if err := store.Clear(); err != nil {
+				return err
+			}
+			utils.LogSuccess("Cache cleared")
+			return nil
+		})
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+// withCache opens the default cache, runs fn and closes the cache afterwards
+func withCache(fn func(store *cache.Store) error) error {
+	store, err := cache.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn(store)
+	if err := store.Close(); err != nil && fnErr == nil {
+		return err
+	}
+	return fnErr
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"clone-spotter/internal/core"
+	"clone-spotter/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareOutputDir    string
+	compareFilename     string
+	compareAlgorithm    string
+	compareExcludeDirs  []string
+	compareExcludeFiles []string
+	compareGitignore    bool
+	compareTerminal     bool
+	compareVerbose      bool
+	compareQuiet        bool
+	compareVerify       bool
+	compareWorkers      int
+	compareNoCache      bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare SOURCE REFERENCE",
+	Short: "Check which files of a source tree already exist in a reference tree",
+	Long: `Hash a source tree and a reference tree and sort every source file into
+one of three lists:
+
+- unique to the source: the reference holds no copy of it
+- duplicated in the reference: the reference holds a copy elsewhere
+- present in both: the reference holds the same content at the same relative path
+
+Source files the search leaves out, such as excluded files and symbolic links,
+are listed as skipped, and files that cannot be read as errors, so no source
+file goes unmentioned. Useful before deleting a folder that should already be
+backed up elsewhere. Empty files count as copies of each other.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	compareCmd.Flags().StringVarP(&compareOutputDir, "output", "o", "./output", "Output directory")
+	compareCmd.Flags().StringVarP(&compareFilename, "filename", "f", "comparison", "Output filename without extension")
+	compareCmd.Flags().StringVarP(&compareAlgorithm, "algorithm", "a", "md5", "Hash algorithm (md5, sha1, sha256, sha512, xxhash64, xxh3, blake3, crc32c)")
+	compareCmd.Flags().StringSliceVarP(&compareExcludeDirs, "exclude", "e", nil, "Directory pattern to exclude, gitignore syntax (repeatable or comma-separated)")
+	compareCmd.Flags().StringSliceVar(&compareExcludeFiles, "exclude-file", nil, "File pattern to exclude, gitignore syntax (repeatable or comma-separated)")
+	compareCmd.Flags().BoolVar(&compareGitignore, "respect-gitignore", false, "Skip paths ignored by .gitignore, .git/info/exclude and .clonespotterignore files instead of the default excluded directories")
+	compareCmd.Flags().BoolVarP(&compareTerminal, "terminal", "t", false, "Also output results to terminal")
+	compareCmd.Flags().BoolVar(&compareVerbose, "verbose", false, "Also list the source files the reference already holds")
+	compareCmd.Flags().BoolVarP(&compareQuiet, "quiet", "q", false, "Minimal output")
+	compareCmd.Flags().BoolVar(&compareVerify, "verify", false, "Confirm every match with a byte-for-byte comparison")
+	compareCmd.Flags().IntVarP(&compareWorkers, "workers", "w", runtime.GOMAXPROCS(0), "Number of concurrent hashing workers (1 uses the sequential engine)")
+	compareCmd.Flags().BoolVar(&compareNoCache, "no-cache", false, "Do not read or write the persistent hash cache")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	if !core.IsValidAlgorithm(compareAlgorithm) {
+		return fmt.Errorf("unsupported algorithm: %s. Supported: %v", compareAlgorithm, core.GetSupportedAlgorithms())
+	}
+
+	source, reference := utils.CleanDirPath(args[0]), utils.CleanDirPath(args[1])
+	for _, dir := range []string{source, reference} {
+		if !core.ValidateDirectory(dir) {
+			return fmt.Errorf("directory not found or not accessible: %s", dir)
+		}
+	}
+
+	excludedDirs := withDefaults(defaultExcludedDirs(compareGitignore), compareExcludeDirs)
+	excludedFiles := withDefaults(core.DefaultExcludedFiles, compareExcludeFiles)
+	if _, err := core.ParseExcludeRules(excludedDirs, excludedFiles); err != nil {
+		return err
+	}
+
+	if compareWorkers < 1 {
+		return fmt.Errorf("workers must be at least 1: %d", compareWorkers)
+	}
+
+	// Arguments are valid; later failures are not usage errors
+	cmd.SilenceUsage = true
+
+	return executeCompare(source, reference, searchOptions{
+		outputDir:     compareOutputDir,
+		filename:      compareFilename,
+		algorithm:     compareAlgorithm,
+		excludedDirs:  excludedDirs,
+		excludedFiles: excludedFiles,
+		gitignore:     compareGitignore,
+		terminal:      compareTerminal,
+		verbose:       compareVerbose,
+		quiet:         compareQuiet,
+		sampleSize:    core.DefaultSampleSize,
+		verify:        compareVerify,
+		noCache:       compareNoCache,
+		workers:       compareWorkers,
+	})
+}
+
+func executeCompare(source, reference string, opts searchOptions) error {
+	quiet := opts.quiet
+
+	if !quiet {
+		utils.LogBold(fmt.Sprintf("\n🚀 %s Starting Comparison", AppName))
+		utils.LogCyan(strings.Repeat("=", 50))
+		utils.LogInfo(fmt.Sprintf("Source: %s", source))
+		utils.LogInfo(fmt.Sprintf("Reference: %s", reference))
+		utils.LogInfo(fmt.Sprintf("Algorithm: %s", opts.algorithm))
+		if len(opts.excludedDirs) > 0 {
+			utils.LogInfo(fmt.Sprintf("Excluded directories: %s", strings.Join(opts.excludedDirs, ", ")))
+		}
+		if len(opts.excludedFiles) > 0 {
+			utils.LogInfo(fmt.Sprintf("Excluded files: %s", strings.Join(opts.excludedFiles, ", ")))
+		}
+		if opts.gitignore {
+			utils.LogInfo("Ignore files: .gitignore, .git/info/exclude, .clonespotterignore")
+		}
+		if opts.verify {
+			utils.LogInfo("Byte-for-byte verification: enabled")
+		}
+		utils.LogInfo(fmt.Sprintf("Workers: %d", opts.workers))
+		utils.LogInfo(fmt.Sprintf("Output: %s", filepath.Join(opts.outputDir, opts.filename+".json")))
+		fmt.Println()
+	}
+
+	finder := newFinder(opts)
+	finder.SetSampleSize(opts.sampleSize)
+	finder.SetVerify(opts.verify)
+	// An empty source file is as safe to delete as any other copied file
+	finder.SetEmptyFilePolicy(core.EmptyDuplicate)
+
+	if !opts.noCache {
+		defer attachCache(finder)()
+	}
+
+	progressChan := make(chan int, 100)
+	go func() {
+		total := 0
+		for range progressChan {
+			total++
+			if !quiet {
+				fmt.Printf("\rProcessing files: %d", total)
+			}
+		}
+		if !quiet {
+			fmt.Println()
+		}
+	}()
+
+	// A partial comparison would report files as unique that are not, so an
+	// interrupted one saves nothing
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	comparison, err := core.CompareTrees(ctx, finder, source, reference, progressChan)
+	stop()
+	close(progressChan)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return errors.New("comparison interrupted; no results saved")
+		}
+		return fmt.Errorf("comparison failed: %w", err)
+	}
+
+	if !quiet {
+		utils.LogSuccess("Comparison completed")
+
+		utils.LogBold("\n📊 Results Summary")
+		utils.LogCyan(strings.Repeat("-", 30))
+		utils.LogInfo(fmt.Sprintf("Present in both at the same path: %d", len(comparison.PresentInBoth)))
+		utils.LogInfo(fmt.Sprintf("Duplicated elsewhere in the reference: %d", len(comparison.DuplicatedInReference)))
+		if len(comparison.UniqueToSource) > 0 {
+			utils.LogWarning(fmt.Sprintf("Unique to the source: %d", len(comparison.UniqueToSource)))
+		} else if len(comparison.Skipped) == 0 && len(comparison.Errors) == 0 {
+			utils.LogSuccess("Every source file has a copy in the reference")
+		}
+		if len(comparison.Skipped) > 0 {
+			utils.LogWarning(fmt.Sprintf("Skipped in the source, not compared: %d", len(comparison.Skipped)))
+		}
+		if len(comparison.Errors) > 0 {
+			utils.LogWarning(fmt.Sprintf("Unreadable files and directories: %d", len(comparison.Errors)))
+		}
+		if collisions := finder.Stats().HashCollisions; collisions > 0 {
+			utils.LogWarning(fmt.Sprintf("Hash collisions (same hash, different content): %d", collisions))
+		}
+	}
+
+	outputPath := utils.MassagePath(opts.outputDir, opts.filename)
+	if err := utils.WriteJSONFile(comparison, outputPath); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+
+	utils.LogSuccess(fmt.Sprintf("Results saved to %s", outputPath))
+
+	if opts.terminal {
+		fmt.Println("\n=== Output Data ===")
+		jsonData, err := json.MarshalIndent(comparison, "", "  ")
+		if err == nil {
+			fmt.Println(string(jsonData))
+		}
+		fmt.Print("==================\n\n")
+	}
+
+	// Files missing from the reference are what the user needs to act on, so
+	// they are always listed, along with the files nothing is known about
+	if !quiet && len(comparison.UniqueToSource) > 0 {
+		utils.LogBold("\n📋 Unique to the source")
+		utils.LogCyan(strings.Repeat("-", 30))
+		for _, path := range comparison.UniqueToSource {
+			fmt.Printf("  %s\n", utils.Red(path))
+		}
+	}
+	if !quiet && len(comparison.Skipped) > 0 {
+		utils.LogBold("\n📋 Skipped in the source")
+		utils.LogCyan(strings.Repeat("-", 30))
+		for _, path := range comparison.Skipped {
+			fmt.Printf("  %s\n", utils.Yellow(path))
+		}
+	}
+	if !quiet && len(comparison.Errors) > 0 {
+		utils.LogBold("\n📋 Unreadable")
+		utils.LogCyan(strings.Repeat("-", 30))
+		for _, fileError := range comparison.Errors {
+			fmt.Printf("  %s: %s\n", utils.Red(fileError.Path), fileError.Error)
+		}
+	}
+
+	if opts.verbose {
+		if len(comparison.DuplicatedInReference) > 0 {
+			utils.LogBold("\n📋 Duplicated in the reference")
+			utils.LogCyan(strings.Repeat("-", 30))
+			for _, file := range comparison.DuplicatedInReference {
+				fmt.Printf("  %s\n", utils.Yellow(file.Path))
+				for _, match := range file.Copies {
+					fmt.Printf("    = %s\n", utils.Green(match))
+				}
+			}
+		}
+		if len(comparison.PresentInBoth) > 0 {
+			utils.LogBold("\n📋 Present in both")
+			utils.LogCyan(strings.Repeat("-", 30))
+			for _, path := range comparison.PresentInBoth {
+				fmt.Printf("  %s\n", utils.Green(path))
+			}
+		}
+	}
+
+	if !quiet {
+		utils.LogBold(fmt.Sprintf("\n🎉 %s Complete!", AppName))
+	}
+
+	return nil
+}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
 
 	"clone-spotter/internal/core"
+	"clone-spotter/internal/report"
 	"clone-spotter/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -26,8 +28,8 @@ func runInteractiveMode() error {
 	utils.LogBold(fmt.Sprintf("\n🔍 %s Interactive Mode", AppName))
 	utils.LogCyan(strings.Repeat("=", 50))
 
-	// Get root directory
-	rootDir, err := promptForDirectory()
+	// Get root directories
+	roots, err := promptForDirectories()
 	if err != nil {
 		return err
 	}
@@ -38,8 +40,26 @@ func runInteractiveMode() error {
 		return err
 	}
 
-	// Get excluded directories
-	excludedDirs, err := promptForExcludedDirs()
+	// Get ignore file preference
+	gitignore, err := promptForGitignore()
+	if err != nil {
+		return err
+	}
+
+	// Get excluded directories and files
+	excludedDirs, excludedFiles, err := promptForExclusions(defaultExcludedDirs(gitignore))
+	if err != nil {
+		return err
+	}
+
+	// Get worker count
+	workers, err := promptForWorkers()
+	if err != nil {
+		return err
+	}
+
+	// Get verification preference
+	verify, err := promptForVerify()
 	if err != nil {
 		return err
 	}
@@ -51,21 +71,47 @@ func runInteractiveMode() error {
 	}
 
 	// Execute search
-	return executeSearch(rootDir, outputDir, filename, algorithm, excludedDirs, terminal, verbose, false)
+	return executeSearch(searchOptions{
+		roots:         roots,
+		outputDir:     outputDir,
+		filename:      filename,
+		algorithm:     algorithm,
+		excludedDirs:  excludedDirs,
+		excludedFiles: excludedFiles,
+		gitignore:     gitignore,
+		terminal:      terminal,
+		verbose:       verbose,
+		sampleSize:    core.DefaultSampleSize,
+		verify:        verify,
+		emptyPolicy:   core.DefaultEmptyFilePolicy,
+		symlinks:      core.DefaultSymlinkPolicy,
+		originalRule:  core.DefaultOriginalRule,
+		format:        report.DefaultFormat,
+		scriptAction:  report.DefaultScriptAction,
+		workers:       workers,
+	})
 }
 
-func promptForDirectory() (string, error) {
+func promptForDirectories() ([]string, error) {
 	reader := bufio.NewReader(os.Stdin)
 	
+	var roots []string
 	for {
-		fmt.Print("\n📁 Directory to search: ")
+		if len(roots) == 0 {
+			fmt.Print("\n📁 Directory to search: ")
+		} else {
+			fmt.Print("📁 Another directory to search (or press Enter to continue): ")
+		}
 		input, err := reader.ReadString('\n')
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		
 		rootDir := strings.TrimSpace(input)
 		if rootDir == "" {
+			if len(roots) > 0 {
+				return roots, nil
+			}
 			utils.LogError("Directory is required")
 			continue
 		}
@@ -76,7 +122,7 @@ func promptForDirectory() (string, error) {
 			continue
 		}
 
-		return cleanRootDir, nil
+		roots = append(roots, cleanRootDir)
 	}
 }
 
@@ -88,6 +134,8 @@ func promptForAlgorithm() (string, error) {
 		marker := ""
 		if algo == core.MD5 {
 			marker = " (default)"
+		} else if !core.IsCryptographic(algo) {
+			marker = " (fast, non-cryptographic)"
 		}
 		fmt.Printf("  %d. %s%s\n", i+1, algo, marker)
 	}
@@ -114,12 +162,50 @@ func promptForAlgorithm() (string, error) {
 	return string(algorithms[choice-1]), nil
 }
 
-func promptForExcludedDirs() ([]string, error) {
-	utils.LogBold("\n🚫 Default excluded directories:")
-	fmt.Printf("  %s\n", strings.Join(core.DefaultExcludedDirs, ", "))
+func promptForGitignore() (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("\n📄 Respect .gitignore and .clonespotterignore files? [y/N]: ")
+
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	return strings.ToLower(strings.TrimSpace(input)) == "y", nil
+}
+
+func promptForExclusions(defaultDirs []string) ([]string, []string, error) {
+	if len(defaultDirs) > 0 {
+		utils.LogBold("\n🚫 Default excluded directories:")
+		fmt.Printf("  %s\n", strings.Join(defaultDirs, ", "))
+	}
+	utils.LogBold("🚫 Default excluded files:")
+	fmt.Printf("  %s\n", strings.Join(core.DefaultExcludedFiles, ", "))
+	utils.LogInfo("Patterns use gitignore syntax, e.g. build, logs/**, *.tmp, !keep.tmp")
 
+	for {
+		excludedDirs, err := promptForPatterns("\n🚫 Additional directories to exclude (comma-separated, or press Enter for default): ", defaultDirs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		excludedFiles, err := promptForPatterns("🚫 Additional files to exclude (comma-separated, or press Enter for default): ", core.DefaultExcludedFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, err := core.ParseExcludeRules(excludedDirs, excludedFiles); err != nil {
+			utils.LogError(err.Error())
+			continue
+		}
+
+		return excludedDirs, excludedFiles, nil
+	}
+}
+
+func promptForPatterns(prompt string, defaults []string) ([]string, error) {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("\n🚫 Additional directories to exclude (comma-separated, or press Enter for default): ")
+	fmt.Print(prompt)
 	
 	input, err := reader.ReadString('\n')
 	if err != nil {
@@ -128,15 +214,46 @@ func promptForExcludedDirs() ([]string, error) {
 	
 	input = strings.TrimSpace(input)
 	if input == "" {
-		return core.DefaultExcludedDirs, nil
+		return defaults, nil
 	}
 	
-	additionalDirs := strings.Split(input, ",")
-	for i, dir := range additionalDirs {
-		additionalDirs[i] = strings.TrimSpace(dir)
+	return withDefaults(defaults, strings.Split(input, ",")), nil
+}
+
+func promptForWorkers() (int, error) {
+	defaultWorkers := runtime.GOMAXPROCS(0)
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("\n⚙️  Concurrent workers, 1 for sequential (default: %d): ", defaultWorkers)
+
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return 0, err
 	}
 	
-	return append(core.DefaultExcludedDirs, additionalDirs...), nil
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultWorkers, nil
+	}
+
+	workers, err := strconv.Atoi(input)
+	if err != nil || workers < 1 {
+		utils.LogWarning(fmt.Sprintf("Invalid worker count, using default (%d)", defaultWorkers))
+		return defaultWorkers, nil
+	}
+
+	return workers, nil
+}
+
+func promptForVerify() (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("\n🔬 Verify duplicates byte-for-byte? [y/N]: ")
+
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	return strings.ToLower(strings.TrimSpace(input)) == "y", nil
 }
 
 func promptForOutput() (string, string, bool, bool, error) {
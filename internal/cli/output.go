@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"clone-spotter/internal/report"
+	"clone-spotter/internal/utils"
+)
+
+// stdoutPath is the output directory that sends results to standard output
+const stdoutPath = "-"
+
+// reportTool identifies this program in reports
+func reportTool() report.Tool {
+	return report.Tool{Name: AppName, Version: AppVersion}
+}
+
+// reportScan describes the parameters of a search for its report
+func reportScan(opts searchOptions, startedAt, finishedAt time.Time, incomplete bool) report.Scan {
+	return report.Scan{
+		Roots:         opts.roots,
+		Algorithm:     opts.algorithm,
+		ExcludedDirs:  opts.excludedDirs,
+		ExcludedFiles: opts.excludedFiles,
+		RespectIgnore: opts.gitignore,
+		Filters:       report.NewFilters(opts.filters),
+		EmptyFiles:    string(opts.emptyPolicy),
+		Symlinks:      string(opts.symlinks),
+		OneFileSystem: opts.oneFS,
+		OriginalRule:  string(opts.originalRule),
+		Verify:        opts.verify,
+		StartedAt:     startedAt,
+		FinishedAt:    finishedAt,
+		Incomplete:    incomplete,
+	}
+}
+
+// outputPath returns the file results are written to, or stdoutPath
+func outputPath(opts searchOptions) string {
+	if opts.outputDir == stdoutPath {
+		return stdoutPath
+	}
+	return utils.OutputPath(opts.outputDir, opts.filename, opts.format.Extension())
+}
+
+// writeOutput writes encoded results to path, or to standard output
+func writeOutput(data []byte, path string) error {
+	if path == stdoutPath {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return utils.WriteOutputFile(data, path)
+}
+
+// reportStream writes an NDJSON report while a search runs
+type reportStream struct {
+	file   *os.File // Nil when writing to standard output
+	writer *report.StreamWriter
+}
+
+// openReportStream creates the output at path and writes the scan record
+func openReportStream(path string, scan report.Scan) (*reportStream, error) {
+	s := &reportStream{}
+	out := os.Stdout
+	if path != stdoutPath {
+		if err := utils.EnsureDirExists(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		s.file, out = file, file
+	}
+
+	s.writer = report.NewStreamWriter(out)
+	if err := s.writer.WriteScan(reportTool(), scan); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// finish writes the summary record of r and closes the output
+func (s *reportStream) finish(r *report.Report) error {
+	err := s.writer.WriteSummary(r)
+	if closeErr := s.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// close closes the output file, if any; it is safe to call more than once
+func (s *reportStream) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
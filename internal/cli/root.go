@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
+	"clone-spotter/internal/cache"
 	"clone-spotter/internal/core"
+	"clone-spotter/internal/report"
 	"clone-spotter/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -19,31 +29,96 @@ const (
 )
 
 var (
-	rootDir     string
-	outputDir   string
-	filename    string
-	algorithm   string
-	excludeDirs string
-	terminal    bool
-	verbose     bool
-	quiet       bool
+	rootDirs     []string
+	outputDir    string
+	filename     string
+	algorithm    string
+	excludeDirs  []string
+	excludeFiles []string
+	gitignore    bool
+	minSize      string
+	maxSize      string
+	includeExt   []string
+	excludeExt   []string
+	names        []string
+	newerThan    string
+	olderThan    string
+	terminal     bool
+	verbose      bool
+	quiet        bool
+	sampleSize   int64
+	verify       bool
+	emptyFiles   string
+	symlinks     string
+	oneFS        bool
+	noCache      bool
+	workers      int
+	legacyFormat bool
+	original     string
+	format       string
+	scriptAction string
+	scriptActive bool
 )
 
+// searchOptions holds everything executeSearch needs to run a scan
+type searchOptions struct {
+	roots         []string
+	outputDir     string
+	filename      string
+	algorithm     string
+	excludedDirs  []string
+	excludedFiles []string
+	gitignore     bool
+	filters       core.FilterOptions
+	terminal      bool
+	verbose       bool
+	quiet         bool
+	sampleSize    int64
+	verify        bool
+	emptyPolicy   core.EmptyFilePolicy
+	symlinks      core.SymlinkPolicy
+	oneFS         bool
+	noCache       bool
+	workers       int
+	legacyFormat  bool
+	originalRule  core.OriginalRule
+	format        report.Format
+	scriptAction  report.ScriptAction
+	scriptActive  bool
+}
+
+// configurableScanner is a core.Scanner whose pipeline can be tuned from
+// flags. Both DuplicateFinder and ConcurrentDuplicateFinder satisfy it.
+type configurableScanner interface {
+	core.Scanner
+	SetSampleSize(size int64)
+	SetVerify(verify bool)
+	SetEmptyFilePolicy(policy core.EmptyFilePolicy)
+	SetSymlinkPolicy(policy core.SymlinkPolicy)
+	SetOneFileSystem(one bool)
+	SetOriginalRule(rule core.OriginalRule)
+	SetGroupHandler(handler core.GroupHandler)
+	SetCache(cache core.HashCache)
+	SetExcludedFiles(patterns []string)
+	SetRespectIgnoreFiles(respect bool)
+	AddFilter(filter core.Filter)
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "clone-spotter [DIRECTORY]",
+	Use:   "clone-spotter [DIRECTORY...]",
 	Short: "Find duplicate files based on content",
 	Long: `A powerful command-line tool that finds duplicate files based on their content, 
 not their names. Perfect for cleaning up your file system and reclaiming disk space.
 
 Features:
 - Content-based Detection: Finds duplicates by comparing file hashes
-- Multiple Hash Algorithms: Support for MD5, SHA1, SHA256, and SHA512
+- Multiple Hash Algorithms: Support for MD5, SHA1, SHA256, SHA512, xxHash64, XXH3, BLAKE3 and CRC32C
 - Configurable Exclusions: Automatically skips common directories
 - Flexible Output: Save results to JSON file with optional terminal output
 - Robust Error Handling: Graceful handling of file system errors
 - Comprehensive Statistics: Detailed reports on duplicate file counts and groups`,
-	Args: cobra.MaximumNArgs(1),
+	Args: cobra.ArbitraryArgs,
 	RunE: runSearch,
 }
 
@@ -54,28 +129,51 @@ func Execute() error {
 
 func init() {
 	// Add flags
-	rootCmd.Flags().StringVarP(&rootDir, "directory", "d", "", "Directory to search for duplicates")
-	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "./output", "Output directory")
+	rootCmd.Flags().StringArrayVarP(&rootDirs, "directory", "d", nil, "Directory to search for duplicates (repeatable)")
+	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "./output", "Output directory, or - for standard output")
 	rootCmd.Flags().StringVarP(&filename, "filename", "f", "duplicates", "Output filename without extension")
-	rootCmd.Flags().StringVarP(&algorithm, "algorithm", "a", "md5", "Hash algorithm (md5, sha1, sha256, sha512)")
-	rootCmd.Flags().StringVarP(&excludeDirs, "exclude", "e", "", "Comma-separated list of directories to exclude")
+	rootCmd.Flags().StringVarP(&algorithm, "algorithm", "a", "md5", "Hash algorithm (md5, sha1, sha256, sha512, xxhash64, xxh3, blake3, crc32c)")
+	rootCmd.Flags().StringSliceVarP(&excludeDirs, "exclude", "e", nil, "Directory pattern to exclude, gitignore syntax (repeatable or comma-separated)")
+	rootCmd.Flags().StringSliceVar(&excludeFiles, "exclude-file", nil, "File pattern to exclude, gitignore syntax (repeatable or comma-separated)")
+	rootCmd.Flags().BoolVar(&gitignore, "respect-gitignore", false, "Skip paths ignored by .gitignore, .git/info/exclude and .clonespotterignore files instead of the default excluded directories")
+	rootCmd.Flags().StringVar(&minSize, "min-size", "", "Skip files smaller than this size, e.g. 1K or 2.5MB")
+	rootCmd.Flags().StringVar(&maxSize, "max-size", "", "Skip files larger than this size, e.g. 100M or 4GB")
+	rootCmd.Flags().StringSliceVar(&includeExt, "include-ext", nil, "Only consider files with these extensions (repeatable or comma-separated)")
+	rootCmd.Flags().StringSliceVar(&excludeExt, "exclude-ext", nil, "Skip files with these extensions (repeatable or comma-separated)")
+	rootCmd.Flags().StringArrayVar(&names, "name", nil, "Only consider files whose name matches this glob (repeatable; commas are part of the glob)")
+	rootCmd.Flags().StringVar(&newerThan, "newer-than", "", "Only consider files modified after this age (e.g. 12h, 30d, 2w) or date (e.g. 2024-01-31)")
+	rootCmd.Flags().StringVar(&olderThan, "older-than", "", "Only consider files modified before this age (e.g. 12h, 30d, 2w) or date (e.g. 2024-01-31)")
 	rootCmd.Flags().BoolVarP(&terminal, "terminal", "t", false, "Also output results to terminal")
 	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "Verbose output with detailed information")
 	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Minimal output")
+	rootCmd.Flags().BoolVar(&verify, "verify", false, "Confirm every duplicate with a byte-for-byte comparison and report hash collisions")
+	rootCmd.Flags().StringVar(&emptyFiles, "empty-files", string(core.DefaultEmptyFilePolicy), "How to handle zero-length files: ignore, report (list separately) or duplicate (group like any other content)")
+	rootCmd.Flags().StringVar(&symlinks, "symlinks", string(core.DefaultSymlinkPolicy), "How to handle symbolic links: skip, report (list without following) or follow")
+	rootCmd.Flags().BoolVarP(&oneFS, "one-file-system", "x", false, "Do not descend into directories on other filesystems")
+	rootCmd.Flags().StringVar(&original, "original", string(core.DefaultOriginalRule), "Which file of a group is the original: path (first in sort order), oldest, shortest or root (under the first directory given)")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", runtime.GOMAXPROCS(0), "Number of concurrent hashing workers (1 uses the sequential engine)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not read or write the persistent hash cache")
+	rootCmd.Flags().Int64Var(&sampleSize, "sample-size", core.DefaultSampleSize, "Bytes hashed from the head and tail of same-size files before full hashing (0 disables)")
+	rootCmd.Flags().StringVar(&format, "format", string(report.DefaultFormat), "Output format: json, csv or tsv (one row per file), ndjson (one record per line, groups written once the walk is over, without waiting for the remaining hashing), html (a page to browse in any browser), sqlite (added to a database holding every scan) or script (a shell script of cleanup commands to review)")
+	rootCmd.Flags().StringVar(&scriptAction, "script-action", string(report.DefaultScriptAction), "What the script format does with each duplicate: rm (delete it) or ln (replace it with a hard link to its original)")
+	rootCmd.Flags().BoolVar(&scriptActive, "script-active", false, "Write the script format's commands uncommented, ready to run")
+	rootCmd.Flags().BoolVar(&legacyFormat, "legacy-format", false, "Write the unversioned JSON output of earlier releases: a map of originals to duplicates")
 
 	// Add version command
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(interactiveCmd)
+	rootCmd.AddCommand(benchmarkCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(schemaCmd)
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
-	// Get root directory from args or flag
-	if len(args) > 0 {
-		rootDir = args[0]
-	}
+	// Get root directories from flags and args
+	dirs := append(append([]string(nil), rootDirs...), args...)
 
 	// If no directory specified, run interactive mode
-	if rootDir == "" {
+	if len(dirs) == 0 {
 		return runInteractiveMode()
 	}
 
@@ -84,35 +182,294 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unsupported algorithm: %s. Supported: %v", algorithm, core.GetSupportedAlgorithms())
 	}
 
-	// Clean and validate root directory
-	cleanRootDir := utils.CleanDirPath(rootDir)
-	if !core.ValidateDirectory(cleanRootDir) {
-		return fmt.Errorf("directory not found or not accessible: %s", cleanRootDir)
+	// Clean and validate root directories
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		cleanRootDir := utils.CleanDirPath(dir)
+		if !core.ValidateDirectory(cleanRootDir) {
+			return fmt.Errorf("directory not found or not accessible: %s", cleanRootDir)
+		}
+		roots = append(roots, cleanRootDir)
+	}
+
+	// Parse excluded directories and files
+	excludedDirs := withDefaults(defaultExcludedDirs(gitignore), excludeDirs)
+	excludedFiles := withDefaults(core.DefaultExcludedFiles, excludeFiles)
+	if _, err := core.ParseExcludeRules(excludedDirs, excludedFiles); err != nil {
+		return err
+	}
+
+	// Validate empty-file policy
+	if !core.IsValidEmptyFilePolicy(emptyFiles) {
+		return fmt.Errorf("unsupported empty-file policy: %s. Supported: %v", emptyFiles, core.GetEmptyFilePolicies())
+	}
+
+	// Validate symlink policy
+	if !core.IsValidSymlinkPolicy(symlinks) {
+		return fmt.Errorf("unsupported symlink policy: %s. Supported: %v", symlinks, core.GetSymlinkPolicies())
+	}
+
+	// Validate original-selection rule
+	if !core.IsValidOriginalRule(original) {
+		return fmt.Errorf("unsupported original rule: %s. Supported: %v", original, core.GetOriginalRules())
+	}
+
+	// Validate output format
+	if !report.IsValidFormat(format) {
+		return fmt.Errorf("unsupported format: %s. Supported: %v", format, report.GetFormats())
+	}
+	if legacyFormat && report.Format(format) != report.FormatJSON {
+		return fmt.Errorf("--legacy-format only applies to the json format")
 	}
 
-	// Parse excluded directories
-	excludedDirs := core.DefaultExcludedDirs
-	if excludeDirs != "" {
-		excludedDirs = append(excludedDirs, strings.Split(excludeDirs, ",")...)
-		// Trim spaces
-		for i, dir := range excludedDirs {
-			excludedDirs[i] = strings.TrimSpace(dir)
+	if !report.IsValidScriptAction(scriptAction) {
+		return fmt.Errorf("unsupported script action: %s. Supported: %v", scriptAction, report.GetScriptActions())
+	}
+	// A hash collision would otherwise put a unique file in the script
+	if report.Format(format) == report.FormatScript && !verify && !core.IsCryptographic(core.HashAlgorithm(algorithm)) {
+		return fmt.Errorf("the script format needs --verify with the non-cryptographic %s algorithm", algorithm)
+	}
+
+	// Streamed groups are not kept for the detailed listing
+	if report.Format(format) == report.FormatNDJSON && verbose {
+		return fmt.Errorf("--verbose cannot be used with the ndjson format")
+	}
+
+	if report.Format(format) == report.FormatSQLite && outputDir == stdoutPath {
+		return fmt.Errorf("the sqlite format cannot be written to standard output")
+	}
+
+	// Results written to standard output must not be mixed with messages
+	if outputDir == stdoutPath {
+		if terminal || verbose {
+			return fmt.Errorf("--terminal and --verbose cannot be used with --output %s", stdoutPath)
 		}
+		quiet = true
+		utils.LogToStderr()
+	}
+
+	// Parse file selection filters
+	filters, err := parseFilterOptions()
+	if err != nil {
+		return err
+	}
+
+	if workers < 1 {
+		return fmt.Errorf("workers must be at least 1: %d", workers)
 	}
 
+	if sampleSize < 0 {
+		return fmt.Errorf("sample size must not be negative: %d", sampleSize)
+	}
+
+	// Arguments are valid; later failures are not usage errors
+	cmd.SilenceUsage = true
+
 	// Execute search
-	return executeSearch(cleanRootDir, outputDir, filename, algorithm, excludedDirs, terminal, verbose, quiet)
+	return executeSearch(searchOptions{
+		roots:         roots,
+		outputDir:     outputDir,
+		filename:      filename,
+		algorithm:     algorithm,
+		excludedDirs:  excludedDirs,
+		excludedFiles: excludedFiles,
+		gitignore:     gitignore,
+		filters:       filters,
+		terminal:      terminal,
+		verbose:       verbose,
+		quiet:         quiet,
+		sampleSize:    sampleSize,
+		verify:        verify,
+		emptyPolicy:   core.EmptyFilePolicy(emptyFiles),
+		symlinks:      core.SymlinkPolicy(symlinks),
+		oneFS:         oneFS,
+		noCache:       noCache,
+		workers:       workers,
+		legacyFormat:  legacyFormat,
+		originalRule:  core.OriginalRule(original),
+		format:        report.Format(format),
+		scriptAction:  report.ScriptAction(scriptAction),
+		scriptActive:  scriptActive,
+	})
+}
+
+// parseFilterOptions builds and validates the file selection filters from flags
+func parseFilterOptions() (core.FilterOptions, error) {
+	var opts core.FilterOptions
+	var err error
+
+	if minSize != "" {
+		if opts.MinSize, err = utils.ParseFileSize(minSize); err != nil {
+			return opts, fmt.Errorf("--min-size: %w", err)
+		}
+	}
+	if maxSize != "" {
+		if opts.MaxSize, err = utils.ParseFileSize(maxSize); err != nil {
+			return opts, fmt.Errorf("--max-size: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if newerThan != "" {
+		if opts.NewerThan, err = utils.ParseTimeBound(newerThan, now); err != nil {
+			return opts, fmt.Errorf("--newer-than: %w", err)
+		}
+	}
+	if olderThan != "" {
+		if opts.OlderThan, err = utils.ParseTimeBound(olderThan, now); err != nil {
+			return opts, fmt.Errorf("--older-than: %w", err)
+		}
+	}
+
+	for _, ext := range includeExt {
+		if ext = core.NormalizeExtension(ext); ext != "" {
+			opts.IncludeExt = append(opts.IncludeExt, ext)
+		}
+	}
+	for _, ext := range excludeExt {
+		if ext = core.NormalizeExtension(ext); ext != "" {
+			opts.ExcludeExt = append(opts.ExcludeExt, ext)
+		}
+	}
+	opts.Names = withDefaults(nil, names)
+
+	if _, err := opts.Filters(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// describeFilters returns a human-readable line for each active filter
+func describeFilters(opts core.FilterOptions) []string {
+	var lines []string
+	if opts.MinSize > 0 {
+		lines = append(lines, fmt.Sprintf("size >= %s", utils.FormatFileSize(opts.MinSize)))
+	}
+	if opts.MaxSize > 0 {
+		lines = append(lines, fmt.Sprintf("size <= %s", utils.FormatFileSize(opts.MaxSize)))
+	}
+	if len(opts.IncludeExt) > 0 {
+		lines = append(lines, fmt.Sprintf("extensions: %s", strings.Join(opts.IncludeExt, ", ")))
+	}
+	if len(opts.ExcludeExt) > 0 {
+		lines = append(lines, fmt.Sprintf("excluded extensions: %s", strings.Join(opts.ExcludeExt, ", ")))
+	}
+	if len(opts.Names) > 0 {
+		lines = append(lines, fmt.Sprintf("names: %s", strings.Join(opts.Names, ", ")))
+	}
+	if !opts.NewerThan.IsZero() {
+		lines = append(lines, fmt.Sprintf("modified after %s", opts.NewerThan.Format("2006-01-02 15:04")))
+	}
+	if !opts.OlderThan.IsZero() {
+		lines = append(lines, fmt.Sprintf("modified before %s", opts.OlderThan.Format("2006-01-02 15:04")))
+	}
+	return lines
+}
+
+// withDefaults returns the default patterns followed by the trimmed extra ones
+func withDefaults(defaults, extra []string) []string {
+	patterns := append([]string(nil), defaults...)
+	for _, pattern := range extra {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// defaultExcludedDirs returns the directories excluded unless the user says
+// otherwise. Ignore files describe a checkout's build outputs and vendored
+// dependencies better than any fixed list, so none is used alongside them.
+func defaultExcludedDirs(respectIgnore bool) []string {
+	if respectIgnore {
+		return nil
+	}
+	return core.DefaultExcludedDirs
 }
 
-func executeSearch(rootDir, outputDir, filename, algorithm string, excludedDirs []string, terminal, verbose, quiet bool) error {
+// newFinder picks the concurrent engine when more than one worker is requested
+// and the sequential engine otherwise
+func newFinder(opts searchOptions) configurableScanner {
+	algo := core.HashAlgorithm(opts.algorithm)
+
+	var finder configurableScanner
+	if opts.workers > 1 {
+		finder = core.NewConcurrentDuplicateFinder(algo, opts.excludedDirs, opts.workers)
+	} else {
+		finder = core.NewDuplicateFinder(algo, opts.excludedDirs)
+	}
+	finder.SetExcludedFiles(opts.excludedFiles)
+	finder.SetRespectIgnoreFiles(opts.gitignore)
+	return finder
+}
+
+// attachCache opens the persistent hash cache and hands it to finder,
+// returning a function that saves and closes it. A finder still works
+// without the cache, so failing to open it is only a warning.
+func attachCache(finder configurableScanner) func() {
+	store, err := cache.OpenDefault()
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Hash cache unavailable, continuing without it: %v", err))
+		return func() {}
+	}
+	finder.SetCache(store)
+	return func() {
+		if err := store.Close(); err != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to save hash cache: %v", err))
+		}
+	}
+}
+
+// crossRootGroups counts the groups of duplicates that span more than one root
+func crossRootGroups(duplicates []core.Duplicate) int {
+	crossRoot := make(map[string]bool)
+	for _, dup := range duplicates {
+		if dup.CrossRoot() {
+			crossRoot[dup.Original] = true
+		}
+	}
+	return len(crossRoot)
+}
+
+func executeSearch(opts searchOptions) error {
+	roots := opts.roots
+	terminal, verbose, quiet := opts.terminal, opts.verbose, opts.quiet
+
 	if !quiet {
 		utils.LogBold(fmt.Sprintf("\n🚀 %s Starting Search", AppName))
 		utils.LogCyan(strings.Repeat("=", 50))
-		utils.LogInfo(fmt.Sprintf("Searching: %s", rootDir))
-		utils.LogInfo(fmt.Sprintf("Algorithm: %s", algorithm))
-		utils.LogInfo(fmt.Sprintf("Excluded: %s", strings.Join(excludedDirs, ", ")))
-		utils.LogInfo(fmt.Sprintf("Output: %s", filepath.Join(outputDir, filename+".json")))
-		
+		utils.LogInfo(fmt.Sprintf("Searching: %s", strings.Join(roots, ", ")))
+		utils.LogInfo(fmt.Sprintf("Algorithm: %s", opts.algorithm))
+		if len(opts.excludedDirs) > 0 {
+			utils.LogInfo(fmt.Sprintf("Excluded directories: %s", strings.Join(opts.excludedDirs, ", ")))
+		}
+		if len(opts.excludedFiles) > 0 {
+			utils.LogInfo(fmt.Sprintf("Excluded files: %s", strings.Join(opts.excludedFiles, ", ")))
+		}
+		if opts.gitignore {
+			utils.LogInfo("Ignore files: .gitignore, .git/info/exclude, .clonespotterignore")
+		}
+		if filters := describeFilters(opts.filters); len(filters) > 0 {
+			utils.LogInfo(fmt.Sprintf("Filters: %s", strings.Join(filters, "; ")))
+		}
+		if opts.sampleSize > 0 {
+			utils.LogInfo(fmt.Sprintf("Sample size: %s", utils.FormatFileSize(opts.sampleSize)))
+		}
+		if opts.verify {
+			utils.LogInfo("Byte-for-byte verification: enabled")
+		}
+		if opts.symlinks != core.DefaultSymlinkPolicy {
+			utils.LogInfo(fmt.Sprintf("Symlinks: %s", opts.symlinks))
+		}
+		if opts.oneFS {
+			utils.LogInfo("Staying on one filesystem")
+		}
+		if opts.originalRule != core.DefaultOriginalRule {
+			utils.LogInfo(fmt.Sprintf("Originals chosen by: %s", opts.originalRule))
+		}
+		utils.LogInfo(fmt.Sprintf("Workers: %d", opts.workers))
+		utils.LogInfo(fmt.Sprintf("Output: %s", outputPath(opts)))
+
 		if terminal {
 			utils.LogInfo("Terminal output: enabled")
 		}
@@ -120,7 +477,46 @@ func executeSearch(rootDir, outputDir, filename, algorithm string, excludedDirs
 	}
 
 	// Create duplicate finder
-	finder := core.NewDuplicateFinder(core.HashAlgorithm(algorithm), excludedDirs)
+	finder := newFinder(opts)
+	finder.SetSampleSize(opts.sampleSize)
+	finder.SetVerify(opts.verify)
+	finder.SetEmptyFilePolicy(opts.emptyPolicy)
+	finder.SetSymlinkPolicy(opts.symlinks)
+	finder.SetOneFileSystem(opts.oneFS)
+	finder.SetOriginalRule(opts.originalRule)
+
+	// Filters run before any file is opened
+	filters, err := opts.filters.Filters()
+	if err != nil {
+		return err
+	}
+	for _, filter := range filters {
+		finder.AddFilter(filter)
+	}
+
+	// Open the persistent hash cache; a scan still runs without it
+	if !opts.noCache {
+		defer attachCache(finder)()
+	}
+
+	// Stream groups to an NDJSON report as they are found
+	path := outputPath(opts)
+	startedAt := time.Now()
+	var stream *reportStream
+	crossRoot := 0
+	if opts.format == report.FormatNDJSON && !opts.legacyFormat {
+		var err error
+		if stream, err = openReportStream(path, reportScan(opts, startedAt, time.Time{}, false)); err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
+		}
+		defer stream.close()
+		finder.SetGroupHandler(func(group []core.Duplicate) {
+			// Written groups are released, so they are counted here
+			crossRoot += crossRootGroups(group)
+			// A failed write is reported when the stream is finished
+			_ = stream.writer.WriteGroup(group)
+		})
+	}
 
 	// Create progress channel
 	progressChan := make(chan int, 100)
@@ -137,21 +533,27 @@ func executeSearch(rootDir, outputDir, filename, algorithm string, excludedDirs
 		}
 	}()
 
-	// Search for duplicates
-	duplicates, err := finder.SearchDuplicates(rootDir, progressChan)
+	// Search for duplicates, stopping early on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	duplicates, err := finder.SearchRoots(ctx, roots, progressChan)
+	finishedAt := time.Now()
+	stop() // A second signal now terminates immediately
 	close(progressChan)
-	
-	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
-	}
 
-	if !quiet {
+	incomplete := false
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("search failed: %w", err)
+		}
+		incomplete = true
+		utils.LogWarning("Search interrupted, saving partial results")
+	} else if !quiet {
 		utils.LogSuccess("Search completed")
 	}
 
 	// Process results
-	duplicateMap := core.GatherDuplicates(duplicates)
-	stats := core.GetDuplicateStats(duplicates)
+	stats := finder.Stats()
+	multiRoot := len(roots) > 1
 
 	if !quiet {
 		utils.LogBold("\n📊 Results Summary")
@@ -159,31 +561,89 @@ func executeSearch(rootDir, outputDir, filename, algorithm string, excludedDirs
 		utils.LogSuccess(fmt.Sprintf("Found %d duplicate files", stats.TotalDuplicates))
 		utils.LogInfo(fmt.Sprintf("Unique originals: %d", stats.UniqueOriginals))
 		utils.LogInfo(fmt.Sprintf("Total duplicate files: %d", stats.TotalDuplicateFiles))
+		if multiRoot {
+			if stream == nil {
+				crossRoot = crossRootGroups(duplicates)
+			}
+			utils.LogInfo(fmt.Sprintf("Groups spanning more than one root: %d", crossRoot))
+		}
+		utils.LogInfo(fmt.Sprintf("Files scanned: %d", stats.FilesScanned))
+		if stats.FilesFiltered > 0 {
+			utils.LogInfo(fmt.Sprintf("Skipped by filters: %d", stats.FilesFiltered))
+		}
+		if stats.HardLinks > 0 {
+			utils.LogInfo(fmt.Sprintf("Links to already-counted files (nothing to reclaim): %d", stats.HardLinks))
+		}
+		if stats.Symlinks > 0 {
+			utils.LogInfo(fmt.Sprintf("Symlinks (listed, not followed): %d", stats.Symlinks))
+		}
+		if stats.EmptyFiles > 0 {
+			if opts.emptyPolicy == core.EmptyReport {
+				utils.LogInfo(fmt.Sprintf("Empty files (listed separately): %d", stats.EmptyFiles))
+			} else {
+				utils.LogInfo(fmt.Sprintf("Empty files (ignored): %d", stats.EmptyFiles))
+			}
+		}
+		utils.LogInfo(fmt.Sprintf("Eliminated by size: %d, by partial hash: %d, fully hashed: %d",
+			stats.SkippedUniqueSize, stats.SkippedPartialHash, stats.FilesHashed))
+		if stats.CacheHits > 0 {
+			utils.LogInfo(fmt.Sprintf("Hashes reused from cache: %d", stats.CacheHits))
+		}
+
+		if stats.HashCollisions > 0 {
+			utils.LogWarning(fmt.Sprintf("Hash collisions (same hash, different content): %d", stats.HashCollisions))
+		}
 
 		if stats.TotalDuplicates > 0 {
-			// Rough estimate of space savings (assuming average file size)
-			estimatedSize := int64(stats.TotalDuplicates) * 1024 // 1KB average
-			utils.LogWarning(fmt.Sprintf("Potential space savings: ~%s", utils.FormatFileSize(estimatedSize)))
+			utils.LogInfo(fmt.Sprintf("Wasted space: %s in duplicate content", utils.FormatFileSize(stats.WastedBytes)))
+			utils.LogWarning(fmt.Sprintf("Potential space savings: %s on disk", utils.FormatFileSize(stats.ReclaimableBytes)))
 		}
 	}
 
 	// Save results
-	outputPath := utils.MassagePath(outputDir, filename)
-	if err := utils.WriteJSONFile(duplicateMap, outputPath); err != nil {
+	scan := reportScan(opts, startedAt, finishedAt, incomplete)
+	var data []byte
+	if stream != nil {
+		// The groups have been written already
+		err = stream.finish(report.New(reportTool(), scan, nil, finder))
+	} else if opts.format == report.FormatSQLite {
+		var scanID int64
+		if err = utils.EnsureDirExists(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		scanID, err = report.New(reportTool(), scan, duplicates, finder).WriteSQLite(path)
+		if err == nil && !quiet {
+			utils.LogInfo(fmt.Sprintf("Stored as scan %d", scanID))
+		}
+	} else {
+		if opts.legacyFormat {
+			// Earlier releases wrote nothing but the map, whatever else was found
+			data, err = json.MarshalIndent(core.GatherDuplicates(duplicates), "", "  ")
+		} else if opts.format == report.FormatScript {
+			var buf bytes.Buffer
+			err = report.New(reportTool(), scan, duplicates, finder).WriteScript(&buf, opts.scriptAction, opts.scriptActive)
+			data = buf.Bytes()
+		} else {
+			data, err = report.New(reportTool(), scan, duplicates, finder).Encode(opts.format)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		err = writeOutput(data, path)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to save results: %w", err)
 	}
 
-	utils.LogSuccess(fmt.Sprintf("Results saved to %s", outputPath))
+	if path != stdoutPath {
+		utils.LogSuccess(fmt.Sprintf("Results saved to %s", path))
+	}
 
 	// Terminal output if requested
-	if terminal {
+	if terminal && len(data) > 0 {
 		fmt.Println("\n=== Output Data ===")
-		// Convert to JSON for display
-		jsonData, err := json.MarshalIndent(duplicateMap, "", "  ")
-		if err == nil {
-			fmt.Println(string(jsonData))
-		}
-		fmt.Println("==================\n")
+		fmt.Println(strings.TrimSuffix(string(data), "\n"))
+		fmt.Print("==================\n\n")
 	}
 
 	// Verbose output
@@ -191,21 +651,38 @@ func executeSearch(rootDir, outputDir, filename, algorithm string, excludedDirs
 		utils.LogBold("\n📋 Detailed Results")
 		utils.LogCyan(strings.Repeat("-", 30))
 
-		count := 0
-		for original, duplicates := range stats.DuplicateGroups {
+		// Tag each file with its root when several were searched
+		fileRoots := make(map[string]string)
+		for _, dup := range duplicates {
+			fileRoots[dup.Original] = dup.OriginalRoot
+			fileRoots[dup.Duplicate] = dup.DuplicateRoot
+		}
+		tag := func(path string) string {
+			if !multiRoot {
+				return path
+			}
+			return fmt.Sprintf("%s [%s]", path, fileRoots[path])
+		}
+
+		// The groups wasting the most space come first
+		for count, original := range stats.GroupsByWaste() {
 			if count >= 10 { // Show first 10 groups
 				utils.LogWarning(fmt.Sprintf("\n... and %d more groups", len(stats.DuplicateGroups)-10))
 				break
 			}
-			fmt.Printf("\n%s", utils.Yellow(fmt.Sprintf("Group %d:", count+1)))
-			fmt.Printf("\n  Original: %s", utils.Green(original))
-			for _, dup := range duplicates {
-				fmt.Printf("\n  Duplicate: %s", utils.Red(dup))
+			wasted := utils.FormatFileSize(stats.GroupWastedBytes[original])
+			fmt.Printf("\n%s", utils.Yellow(fmt.Sprintf("Group %d (%s wasted):", count+1, wasted)))
+			fmt.Printf("\n  Original: %s", utils.Green(tag(original)))
+			for _, dup := range stats.DuplicateGroups[original] {
+				fmt.Printf("\n  Duplicate: %s", utils.Red(tag(dup)))
 			}
-			count++
 		}
 	}
 
+	if incomplete {
+		return fmt.Errorf("search interrupted; partial results saved to %s", path)
+	}
+
 	if !quiet {
 		utils.LogBold(fmt.Sprintf("\n🎉 %s Complete!", AppName))
 	}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"fmt"
+
+	"clone-spotter/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the report",
+	Long: fmt.Sprintf(`Print the JSON Schema describing the JSON report written by a search,
+version %d, so downstream tools can validate reports before reading them.`, report.SchemaVersion),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := cmd.OutOrStdout().Write(report.Schema)
+		return err
+	},
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// AllocatedSize returns the disk space allocated to a file, which differs
+// from its length for sparse files, files in compressed or deduplicating
+// filesystems and files whose tail fills only part of a block
+func AllocatedSize(info os.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	// st_blocks counts 512-byte units regardless of the filesystem block size
+	return int64(stat.Blocks) * 512
+}
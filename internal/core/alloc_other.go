@@ -0,0 +1,11 @@
+//go:build !linux
+
+package core
+
+import "os"
+
+// AllocatedSize returns the disk space allocated to a file. Allocation is
+// only read on Linux, so elsewhere this is the file's length.
+func AllocatedSize(info os.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"time"
+)
+
+// BenchmarkResult holds the measured hashing throughput of one algorithm
+type BenchmarkResult struct {
+	Algorithm HashAlgorithm
+	Bytes     int64
+	Duration  time.Duration
+}
+
+// Throughput returns the measured throughput in bytes per second
+func (r BenchmarkResult) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// BenchmarkAlgorithms hashes data rounds times with every supported algorithm
+// and reports how long each one took. Hashing happens in memory so the
+// results reflect CPU cost rather than storage speed.
+func BenchmarkAlgorithms(data []byte, rounds int) []BenchmarkResult {
+	if rounds <= 0 {
+		rounds = 1
+	}
+
+	results := make([]BenchmarkResult, 0, len(GetSupportedAlgorithms()))
+	for _, algo := range GetSupportedAlgorithms() {
+		start := time.Now()
+		for i := 0; i < rounds; i++ {
+			hash := NewHash(algo)
+			hash.Write(data)
+			hash.Sum(nil)
+		}
+
+		results = append(results, BenchmarkResult{
+			Algorithm: algo,
+			Bytes:     int64(len(data)) * int64(rounds),
+			Duration:  time.Since(start),
+		})
+	}
+
+	return results
+}
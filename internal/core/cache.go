@@ -0,0 +1,36 @@
+package core
+
+import (
+	"os"
+)
+
+// CacheKey identifies one version of a file's contents for a given algorithm.
+// A cached digest is only reused when every field matches. Path is absolute,
+// so a file is found again whatever directory a later search runs from.
+type CacheKey struct {
+	Path      string
+	Size      int64
+	ModTime   int64
+	Inode     uint64
+	Algorithm HashAlgorithm
+}
+
+// HashCache stores file digests between runs so unchanged files need not be
+// re-hashed
+type HashCache interface {
+	Lookup(key CacheKey) (string, bool)
+	Store(key CacheKey, hash string) error
+}
+
+// NewCacheKey builds the cache key for a file from its absolute path and its
+// stat information
+func NewCacheKey(path string, info os.FileInfo, algorithm HashAlgorithm) CacheKey {
+	_, ino, _ := FileIdentity(info)
+	return CacheKey{
+		Path:      path,
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UnixNano(),
+		Inode:     ino,
+		Algorithm: algorithm,
+	}
+}
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// ReferenceCopies is a source file along with its copies in the reference
+// tree, none of which is at the same relative path
+type ReferenceCopies struct {
+	Path   string   `json:"path"`
+	Copies []string `json:"copies"`
+}
+
+// Comparison describes how the files of a source tree relate to those of a
+// reference tree. Every file in the source appears in exactly one of the
+// three comparison lists, in Skipped or in Errors.
+type Comparison struct {
+	Source    string `json:"source"`
+	Reference string `json:"reference"`
+
+	// UniqueToSource lists source files with no copy anywhere in the reference
+	UniqueToSource []string `json:"uniqueToSource"`
+	// DuplicatedInReference lists source files copied elsewhere in the
+	// reference
+	DuplicatedInReference []ReferenceCopies `json:"duplicatedInReference"`
+	// PresentInBoth lists source files whose relative path holds the same
+	// content in the reference
+	PresentInBoth []string `json:"presentInBoth"`
+
+	// Skipped lists source files the search left out, such as excluded
+	// files, symbolic links and files rejected by the scanner's filters.
+	// Nothing is known about their copies.
+	Skipped []string `json:"skipped"`
+	// Errors lists the files and directories of either tree that could not
+	// be read. A source file listed here is in no other list.
+	Errors []FileError `json:"errors"`
+}
+
+// CompareTrees searches source and reference as one tree with scanner and
+// classifies every source file by whether, and where, the reference holds a
+// copy of it. Hard links count as copies. Source files the search did not
+// hash are listed as skipped or as errors rather than left out. Since an
+// incomplete search would wrongly report files as unique, a cancelled
+// comparison returns no result. The scanner is left as it was, so it can be
+// reused; it must be one of the built-in scanners, which tell CompareTrees
+// which files they admit.
+func CompareTrees(ctx context.Context, scanner Scanner, source, reference string, progressChan chan<- int) (*Comparison, error) {
+	// Record every file the walk admits; files are admitted in the walking
+	// goroutine, so no locking is needed
+	var sourceFiles []string
+	ctx = withAdmitHook(ctx, func(file FileEntry) {
+		if file.Root == source {
+			sourceFiles = append(sourceFiles, file.Path)
+		}
+	})
+
+	duplicates, err := scanner.SearchRoots(ctx, []string{source, reference}, progressChan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gather files with the same content into classes
+	classes := newPathClasses()
+	for _, dup := range duplicates {
+		classes.union(dup.Original, dup.Duplicate)
+	}
+	for target, links := range scanner.HardLinks() {
+		for _, link := range links {
+			classes.union(target, link)
+		}
+	}
+	members := make(map[string][]string)
+	for path := range classes.parent {
+		root := classes.find(path)
+		members[root] = append(members[root], path)
+	}
+
+	admitted := make(map[string]bool, len(sourceFiles))
+	for _, path := range sourceFiles {
+		admitted[path] = true
+	}
+	skipped, unreadable := skippedFiles(source, admitted)
+
+	comparison := &Comparison{
+		Source:                source,
+		Reference:             reference,
+		UniqueToSource:        []string{},
+		DuplicatedInReference: []ReferenceCopies{},
+		PresentInBoth:         []string{},
+		Skipped:               skipped,
+		Errors:                append(append([]FileError{}, scanner.Errors()...), unreadable...),
+	}
+	failed := make(map[string]bool, len(comparison.Errors))
+	for _, fileError := range comparison.Errors {
+		failed[fileError.Path] = true
+	}
+	for _, path := range sourceFiles {
+		if failed[path] {
+			continue
+		}
+		var copies []string
+		samePath := false
+		if _, ok := classes.parent[path]; ok {
+			rel, _ := filepath.Rel(source, path)
+			for _, member := range members[classes.find(path)] {
+				if !pathWithin(member, reference) {
+					continue
+				}
+				copies = append(copies, member)
+				if memberRel, err := filepath.Rel(reference, member); err == nil && memberRel == rel {
+					samePath = true
+				}
+			}
+		}
+
+		switch {
+		case samePath:
+			comparison.PresentInBoth = append(comparison.PresentInBoth, path)
+		case len(copies) > 0:
+			sort.Strings(copies)
+			comparison.DuplicatedInReference = append(comparison.DuplicatedInReference, ReferenceCopies{Path: path, Copies: copies})
+		default:
+			comparison.UniqueToSource = append(comparison.UniqueToSource, path)
+		}
+	}
+
+	sort.Strings(comparison.UniqueToSource)
+	sort.Strings(comparison.PresentInBoth)
+	sort.Slice(comparison.DuplicatedInReference, func(i, j int) bool {
+		return comparison.DuplicatedInReference[i].Path < comparison.DuplicatedInReference[j].Path
+	})
+	sort.Strings(comparison.Skipped)
+	sort.SliceStable(comparison.Errors, func(i, j int) bool {
+		return comparison.Errors[i].Path < comparison.Errors[j].Path
+	})
+	return comparison, nil
+}
+
+// admitHookKey is the context key of a function a search calls with every
+// file that passes its filters
+type admitHookKey struct{}
+
+// withAdmitHook returns a context that makes a search call hook with every
+// file it admits, for that search only
+func withAdmitHook(ctx context.Context, hook func(file FileEntry)) context.Context {
+	return context.WithValue(ctx, admitHookKey{}, hook)
+}
+
+// skippedFiles walks source without any exclusions and returns the files the
+// search did not admit, along with the directories that could not be read
+func skippedFiles(source string, admitted map[string]bool) ([]string, []FileError) {
+	skipped := []string{}
+	var unreadable []FileError
+	// The trailing separator follows a source that is a symbolic link to a
+	// directory, as the search does
+	filepath.WalkDir(source+string(filepath.Separator), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			unreadable = append(unreadable, FileError{Path: filepath.Clean(path), Error: err.Error()})
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !admitted[path] {
+			skipped = append(skipped, path)
+		}
+		return nil
+	})
+	return skipped, unreadable
+}
+
+// pathClasses is a union-find over paths, merging paths known to hold the
+// same content
+type pathClasses struct {
+	parent map[string]string
+}
+
+func newPathClasses() *pathClasses {
+	return &pathClasses{parent: make(map[string]string)}
+}
+
+// find returns the representative of a path's class
+func (c *pathClasses) find(path string) string {
+	for c.parent[path] != path {
+		c.parent[path] = c.parent[c.parent[path]]
+		path = c.parent[path]
+	}
+	return path
+}
+
+// union merges the classes of two paths
+func (c *pathClasses) union(a, b string) {
+	for _, path := range []string{a, b} {
+		if _, ok := c.parent[path]; !ok {
+			c.parent[path] = path
+		}
+	}
+	if rootA, rootB := c.find(a), c.find(b); rootA != rootB {
+		c.parent[rootB] = rootA
+	}
+}
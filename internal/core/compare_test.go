@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareTreesAccountsForEverySourceFile(t *testing.T) {
+	top := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(top, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/same", "at the same path")
+	write("src/moved", "somewhere else")
+	write("src/unique", "only in the source")
+	write("src/node_modules/dep.js", "only in the source")
+	write("src/.DS_Store", "finder metadata")
+	write("ref/same", "at the same path")
+	write("ref/elsewhere/moved", "somewhere else")
+	if err := os.Symlink("same", filepath.Join(top, "src", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	source, reference := filepath.Join(top, "src"), filepath.Join(top, "ref")
+	finder := NewDuplicateFinder(MD5, DefaultExcludedDirs)
+	finder.SetExcludedFiles(DefaultExcludedFiles)
+	comparison, err := CompareTrees(context.Background(), finder, source, reference, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := func(paths ...string) []string {
+		full := make([]string, len(paths))
+		for i, path := range paths {
+			full[i] = filepath.Join(source, filepath.FromSlash(path))
+		}
+		return full
+	}
+	if want := in("same"); !reflect.DeepEqual(comparison.PresentInBoth, want) {
+		t.Errorf("PresentInBoth = %v, want %v", comparison.PresentInBoth, want)
+	}
+	if got := comparison.DuplicatedInReference; len(got) != 1 || got[0].Path != in("moved")[0] {
+		t.Errorf("DuplicatedInReference = %v, want %s", got, in("moved")[0])
+	}
+	if want := in("unique"); !reflect.DeepEqual(comparison.UniqueToSource, want) {
+		t.Errorf("UniqueToSource = %v, want %v", comparison.UniqueToSource, want)
+	}
+	if want := in(".DS_Store", "link", "node_modules/dep.js"); !reflect.DeepEqual(comparison.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", comparison.Skipped, want)
+	}
+	if len(comparison.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", comparison.Errors)
+	}
+}
+
+func TestCompareTreesReusesScanner(t *testing.T) {
+	top := t.TempDir()
+	for path, content := range map[string]string{
+		"first/src/kept":  "copied",
+		"first/ref/kept":  "copied",
+		"second/src/lone": "only in the source",
+		"second/ref/else": "something else",
+	} {
+		full := filepath.Join(top, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sequential := NewDuplicateFinder(MD5, nil)
+	concurrent := NewConcurrentDuplicateFinder(MD5, nil, 4)
+	finders := map[string]struct {
+		Scanner
+		pipeline *pipeline
+	}{
+		"sequential": {sequential, &sequential.pipeline},
+		"concurrent": {concurrent, &concurrent.pipeline},
+	}
+	for name, finder := range finders {
+		for _, tree := range []string{"first", "second", "first"} {
+			source, reference := filepath.Join(top, tree, "src"), filepath.Join(top, tree, "ref")
+			comparison, err := CompareTrees(context.Background(), finder.Scanner, source, reference, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var present, unique []string
+			if tree == "first" {
+				present = []string{filepath.Join(source, "kept")}
+			} else {
+				unique = []string{filepath.Join(source, "lone")}
+			}
+			if len(comparison.PresentInBoth) != len(present) || len(present) > 0 && comparison.PresentInBoth[0] != present[0] {
+				t.Errorf("%s, %s: PresentInBoth = %v, want %v", name, tree, comparison.PresentInBoth, present)
+			}
+			if len(comparison.UniqueToSource) != len(unique) || len(unique) > 0 && comparison.UniqueToSource[0] != unique[0] {
+				t.Errorf("%s, %s: UniqueToSource = %v, want %v", name, tree, comparison.UniqueToSource, unique)
+			}
+			if len(comparison.Skipped) != 0 {
+				t.Errorf("%s, %s: Skipped = %v, want none", name, tree, comparison.Skipped)
+			}
+		}
+		if len(finder.pipeline.filters) != 0 {
+			t.Errorf("%s: comparisons left %d filters on the scanner", name, len(finder.pipeline.filters))
+		}
+	}
+}
@@ -1,28 +1,14 @@
 package core
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
-	"fmt"
-	"hash"
-	"io"
-	"os"
-	"path/filepath"
-	"regexp"
+	"context"
 	"sync"
 )
 
 // ConcurrentDuplicateFinder handles concurrent duplicate detection
 type ConcurrentDuplicateFinder struct {
-	algorithm     HashAlgorithm
-	excludedDirs  []string
-	excludedRegex *regexp.Regexp
-	fileHashes    map[string]string
-	duplicates    []Duplicate
-	mu            sync.RWMutex
-	workerCount   int
+	pipeline
+	workerCount int
 }
 
 // NewConcurrentDuplicateFinder creates a new concurrent duplicate finder
@@ -31,174 +17,118 @@ func NewConcurrentDuplicateFinder(algorithm HashAlgorithm, excludedDirs []string
 		workerCount = 4 // Default to 4 workers
 	}
 
-	df := &ConcurrentDuplicateFinder{
-		algorithm:    algorithm,
-		excludedDirs: excludedDirs,
-		fileHashes:   make(map[string]string),
-		duplicates:   make([]Duplicate, 0),
-		workerCount:  workerCount,
+	return &ConcurrentDuplicateFinder{
+		pipeline:    newPipeline(algorithm, excludedDirs),
+		workerCount: workerCount,
 	}
-
-	// Create regex for excluded directories
-	if len(excludedDirs) > 0 {
-		pattern := ""
-		for i, dir := range excludedDirs {
-			if i > 0 {
-				pattern += "|"
-			}
-			pattern += regexp.QuoteMeta(dir)
-		}
-		df.excludedRegex = regexp.MustCompile(pattern)
-	}
-
-	return df
 }
 
-// isExcluded checks if a path should be excluded from scanning
-func (df *ConcurrentDuplicateFinder) isExcluded(path string) bool {
-	if df.excludedRegex == nil {
-		return false
-	}
-	return df.excludedRegex.MatchString(path)
+// stageWork is a candidate queued for a worker, along with the stage it
+// should resume at
+type stageWork struct {
+	stage     int
+	candidate candidate
 }
 
-// processFile processes a single file and checks for duplicates
-func (df *ConcurrentDuplicateFinder) processFile(filePath string) error {
-	hash, err := df.calculateFileHash(filePath)
-	if err != nil {
-		return err
-	}
-
-	df.mu.Lock()
-	defer df.mu.Unlock()
-
-	if originalPath, exists := df.fileHashes[hash]; exists {
-		df.duplicates = append(df.duplicates, Duplicate{
-			Original:  originalPath,
-			Duplicate: filePath,
-		})
-	} else {
-		df.fileHashes[hash] = filePath
-	}
-
-	return nil
+// SearchDuplicates finds duplicate files using concurrent processing.
+// Files are streamed to the workers while the walk is still running: the
+// walker applies the first (cheapest) stage itself, holding back the first
+// file of each size until a second file of the same size turns up, so files
+// with a unique size are never read.
+func (df *ConcurrentDuplicateFinder) SearchDuplicates(rootDir string, progressChan chan<- int) ([]Duplicate, error) {
+	return df.SearchDuplicatesContext(context.Background(), rootDir, progressChan)
 }
 
-// collectFiles recursively collects all files to process
-func (df *ConcurrentDuplicateFinder) collectFiles(rootDir string) ([]string, error) {
-	var files []string
-	
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			// Log warning but continue
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-			return nil
-		}
-		
-		if info.IsDir() && df.isExcluded(path) {
-			return filepath.SkipDir
-		}
-		
-		if !info.IsDir() {
-			files = append(files, path)
-		}
-		
-		return nil
-	})
-	
-	return files, err
+// SearchDuplicatesContext is like SearchDuplicates but stops promptly when
+// ctx is cancelled: the walk ends, queued work is dropped and in-flight
+// hashes are abandoned. It returns the duplicates found so far and ctx.Err().
+func (df *ConcurrentDuplicateFinder) SearchDuplicatesContext(ctx context.Context, rootDir string, progressChan chan<- int) ([]Duplicate, error) {
+	return df.SearchRoots(ctx, []string{rootDir}, progressChan)
 }
 
-// SearchDuplicatesConcurrent finds duplicate files using concurrent processing
-func (df *ConcurrentDuplicateFinder) SearchDuplicatesConcurrent(rootDir string, progressChan chan<- int) ([]Duplicate, error) {
-	// Verify root directory exists
-	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("directory does not exist: %s", rootDir)
+// SearchRoots is like SearchDuplicatesContext but searches several root
+// directories, in order, as one tree
+func (df *ConcurrentDuplicateFinder) SearchRoots(ctx context.Context, roots []string, progressChan chan<- int) ([]Duplicate, error) {
+	// Verify root directories exist and do not overlap
+	if err := verifyRoots(roots); err != nil {
+		return nil, err
 	}
 
 	// Reset state
-	df.fileHashes = make(map[string]string)
-	df.duplicates = make([]Duplicate, 0)
-
-	// Collect all files first
-	files, err := df.collectFiles(rootDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect files: %w", err)
+	if err := df.reset(roots); err != nil {
+		return nil, err
 	}
 
-	// Create channels for work distribution
-	fileChan := make(chan string, len(files))
-	resultChan := make(chan error, len(files))
-
 	// Start workers
+	workChan := make(chan stageWork, df.workerCount*4)
 	var wg sync.WaitGroup
 	for i := 0; i < df.workerCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for filePath := range fileChan {
-				if err := df.processFile(filePath); err != nil {
-					// Log warning but continue
-					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				}
-				resultChan <- nil
-				if progressChan != nil {
-					progressChan <- 1
+			for work := range workChan {
+				df.advance(ctx, work.stage, work.candidate, progressChan)
+				if df.groupHandler != nil {
+					df.settled(work.candidate.bucket)
 				}
 			}
 		}()
 	}
 
-	// Send files to workers
-	go func() {
-		for _, file := range files {
-			fileChan <- file
+	// Walk the trees, sending each candidate that survives the first stage
+	// to the workers
+	var walkErr error
+	for _, root := range roots {
+		walkErr = df.activeWalker.Walk(root, func(file FileEntry) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			file.Root = root
+			if !df.admit(ctx, file, progressChan) {
+				return nil
+			}
+
+			c := candidate{file: file}
+			if len(df.activeStages) == 0 {
+				df.queued(c.bucket)
+				workChan <- stageWork{stage: 0, candidate: c}
+				return nil
+			}
+			for _, next := range df.runStage(ctx, 0, c, progressChan) {
+				df.queued(next.bucket)
+				workChan <- stageWork{stage: 1, candidate: next}
+			}
+			return nil
+		})
+		if walkErr != nil {
+			break
 		}
-		close(fileChan)
-	}()
-
-	// Wait for all workers to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results
-	for range resultChan {
-		// Results are handled by workers
 	}
-
-	return df.duplicates, nil
-}
-
-// calculateFileHash calculates the hash of a file (same as DuplicateFinder)
-func (df *ConcurrentDuplicateFinder) calculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file %s: %w", filePath, err)
+	// No file can join a group once the walk is over, so the groups of the
+	// buckets the workers have finished can be handed out while they work
+	// on the rest
+	if walkErr == nil && ctx.Err() == nil {
+		df.walked()
 	}
-	defer file.Close()
+	close(workChan)
+	wg.Wait()
 
-	hash := df.getHashAlgorithm()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", err)
+	if ctx.Err() != nil {
+		df.flushGroups()
+		return df.duplicates(), ctx.Err()
 	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	df.finish(progressChan)
+	df.flushGroups()
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return df.duplicates(), nil
 }
 
-// getHashAlgorithm returns the appropriate hash.Hash for the given algorithm
-func (df *ConcurrentDuplicateFinder) getHashAlgorithm() hash.Hash {
-	switch df.algorithm {
-	case MD5:
-		return md5.New()
-	case SHA1:
-		return sha1.New()
-	case SHA256:
-		return sha256.New()
-	case SHA512:
-		return sha512.New()
-	default:
-		return md5.New() // Default to MD5
-	}
+// SearchDuplicatesConcurrent is an alias for SearchDuplicates, kept for
+// existing callers
+func (df *ConcurrentDuplicateFinder) SearchDuplicatesConcurrent(rootDir string, progressChan chan<- int) ([]Duplicate, error) {
+	return df.SearchDuplicates(rootDir, progressChan)
 }
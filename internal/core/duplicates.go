@@ -1,17 +1,9 @@
 package core
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
-	"fmt"
-	"hash"
-	"io"
+	"context"
 	"os"
-	"path/filepath"
-	"regexp"
-	"sync"
+	"sort"
 )
 
 // HashAlgorithm represents the supported hash algorithms
@@ -22,29 +14,120 @@ const (
 	SHA1   HashAlgorithm = "sha1"
 	SHA256 HashAlgorithm = "sha256"
 	SHA512 HashAlgorithm = "sha512"
+
+	// Fast non-cryptographic algorithms, suited to deduplication on fast storage
+	XXHash64 HashAlgorithm = "xxhash64"
+	XXH3     HashAlgorithm = "xxh3"
+	CRC32C   HashAlgorithm = "crc32c"
+
+	// BLAKE3 is cryptographic but considerably faster than the SHA family
+	BLAKE3 HashAlgorithm = "blake3"
+)
+
+// DefaultSampleSize is the default number of bytes hashed from both the head
+// and the tail of a file during the partial-hash stage
+const DefaultSampleSize int64 = 4096
+
+// EmptyFilePolicy decides what happens to zero-length files, which all share
+// the same content and would otherwise form one huge duplicate group
+type EmptyFilePolicy string
+
+const (
+	// EmptyIgnore leaves empty files out of the results entirely
+	EmptyIgnore EmptyFilePolicy = "ignore"
+	// EmptyReport lists empty files separately from the duplicates
+	EmptyReport EmptyFilePolicy = "report"
+	// EmptyDuplicate treats empty files like any other content
+	EmptyDuplicate EmptyFilePolicy = "duplicate"
 )
 
+// DefaultEmptyFilePolicy is the policy used unless another is set
+const DefaultEmptyFilePolicy = EmptyReport
+
+// GetEmptyFilePolicies returns the supported empty-file policies
+func GetEmptyFilePolicies() []EmptyFilePolicy {
+	return []EmptyFilePolicy{EmptyIgnore, EmptyReport, EmptyDuplicate}
+}
+
+// IsValidEmptyFilePolicy checks if the given policy is supported
+func IsValidEmptyFilePolicy(policy string) bool {
+	for _, supported := range GetEmptyFilePolicies() {
+		if EmptyFilePolicy(policy) == supported {
+			return true
+		}
+	}
+	return false
+}
+
 // DefaultExcludedDirs are the default directories to exclude from scanning
 var DefaultExcludedDirs = []string{
 	"node_modules",
 	".git",
-	".DS_Store",
 	"dist",
 	"build",
 }
 
-// Duplicate represents a duplicate file pair
+// DefaultExcludedFiles are the default files to exclude from scanning
+var DefaultExcludedFiles = []string{
+	".DS_Store",
+}
+
+// Duplicate represents a duplicate file pair, along with the root directory
+// each file was found under
 type Duplicate struct {
-	Original  string `json:"original"`
-	Duplicate string `json:"duplicate"`
+	Original      string `json:"original"`
+	Duplicate     string `json:"duplicate"`
+	OriginalRoot  string `json:"originalRoot,omitempty"`
+	DuplicateRoot string `json:"duplicateRoot,omitempty"`
+	// Hash is the content digest the two files share
+	Hash string `json:"hash"`
+	// Size and Allocated are the duplicate's length and the disk space
+	// allocated to it, which deleting it would free. A duplicate with hard
+	// links frees its space only once every link is deleted, so Allocated
+	// is zero unless the search found all of them. OriginalAllocated is the
+	// space allocated to the original.
+	Size              int64 `json:"size"`
+	Allocated         int64 `json:"allocated"`
+	OriginalAllocated int64 `json:"originalAllocated"`
+	// OriginalModTime and DuplicateModTime are the files' modification
+	// times in Unix nanoseconds
+	OriginalModTime  int64 `json:"originalModTime"`
+	DuplicateModTime int64 `json:"duplicateModTime"`
+	// Unreadable lists the files of the same size the search could not
+	// read, sorted by path. Any of them may hold the same content, so the
+	// group may be missing members.
+	Unreadable []string `json:"unreadable,omitempty"`
+}
+
+// CrossRoot reports whether the original and the duplicate were found under
+// different root directories
+func (d Duplicate) CrossRoot() bool {
+	return d.OriginalRoot != d.DuplicateRoot
 }
 
 // DuplicateStats contains statistics about found duplicates
 type DuplicateStats struct {
-	TotalDuplicates     int                    `json:"totalDuplicates"`
-	UniqueOriginals     int                    `json:"uniqueOriginals"`
-	TotalDuplicateFiles int                    `json:"totalDuplicateFiles"`
-	DuplicateGroups     map[string][]string    `json:"duplicateGroups"`
+	TotalDuplicates     int                 `json:"totalDuplicates"`
+	UniqueOriginals     int                 `json:"uniqueOriginals"`
+	TotalDuplicateFiles int                 `json:"totalDuplicateFiles"`
+	DuplicateGroups     map[string][]string `json:"duplicateGroups"`
+	// WastedBytes is the content length of every duplicate beyond its
+	// original, and ReclaimableBytes the disk space deleting them would free.
+	// GroupWastedBytes breaks WastedBytes down by original.
+	WastedBytes        int64            `json:"wastedBytes"`
+	ReclaimableBytes   int64            `json:"reclaimableBytes"`
+	GroupWastedBytes   map[string]int64 `json:"groupWastedBytes"`
+	FilesScanned       int              `json:"filesScanned"`
+	FilesFiltered      int              `json:"filesFiltered"`
+	EmptyFiles         int              `json:"emptyFiles"`
+	HardLinks          int              `json:"hardLinks"`
+	Symlinks           int              `json:"symlinks"`
+	SkippedUniqueSize  int              `json:"skippedUniqueSize"`
+	SkippedPartialHash int              `json:"skippedPartialHash"`
+	StageEliminations  map[string]int   `json:"stageEliminations"`
+	FilesHashed        int              `json:"filesHashed"`
+	CacheHits          int              `json:"cacheHits"`
+	HashCollisions     int              `json:"hashCollisions"`
 }
 
 // FileHash represents a file with its hash
@@ -53,148 +136,69 @@ type FileHash struct {
 	Hash string
 }
 
-// DuplicateFinder handles the duplicate detection logic
+// DuplicateFinder handles the duplicate detection logic, running every stage
+// of the pipeline in the calling goroutine
 type DuplicateFinder struct {
-	algorithm     HashAlgorithm
-	excludedDirs  []string
-	excludedRegex *regexp.Regexp
-	fileHashes    map[string]string
-	duplicates    []Duplicate
-	mu            sync.RWMutex
+	pipeline
 }
 
 // NewDuplicateFinder creates a new DuplicateFinder instance
 func NewDuplicateFinder(algorithm HashAlgorithm, excludedDirs []string) *DuplicateFinder {
-	df := &DuplicateFinder{
-		algorithm:    algorithm,
-		excludedDirs: excludedDirs,
-		fileHashes:   make(map[string]string),
-		duplicates:   make([]Duplicate, 0),
-	}
-
-	// Create regex for excluded directories
-	if len(excludedDirs) > 0 {
-		pattern := ""
-		for i, dir := range excludedDirs {
-			if i > 0 {
-				pattern += "|"
-			}
-			pattern += regexp.QuoteMeta(dir)
-		}
-		df.excludedRegex = regexp.MustCompile(pattern)
-	}
-
-	return df
+	return &DuplicateFinder{pipeline: newPipeline(algorithm, excludedDirs)}
 }
 
-// getHashAlgorithm returns the appropriate hash.Hash for the given algorithm
-func (df *DuplicateFinder) getHashAlgorithm() hash.Hash {
-	switch df.algorithm {
-	case MD5:
-		return md5.New()
-	case SHA1:
-		return sha1.New()
-	case SHA256:
-		return sha256.New()
-	case SHA512:
-		return sha512.New()
-	default:
-		return md5.New() // Default to MD5
-	}
-}
-
-// calculateFileHash calculates the hash of a file
-func (df *DuplicateFinder) calculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file %s: %w", filePath, err)
-	}
-	defer file.Close()
-
-	hash := df.getHashAlgorithm()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
-	}
-
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+// SearchDuplicates finds duplicate files in the specified directory.
+// The search runs as a staged pipeline: files are grouped by size as they are
+// walked, same-size files are compared by a head/tail sample, and only files
+// whose samples collide are fully hashed. The original of each group is
+// chosen by the original-selection rule, so it never depends on walk order.
+func (df *DuplicateFinder) SearchDuplicates(rootDir string, progressChan chan<- int) ([]Duplicate, error) {
+	return df.SearchDuplicatesContext(context.Background(), rootDir, progressChan)
 }
 
-// isExcluded checks if a path should be excluded from scanning
-func (df *DuplicateFinder) isExcluded(path string) bool {
-	if df.excludedRegex == nil {
-		return false
-	}
-	return df.excludedRegex.MatchString(path)
+// SearchDuplicatesContext is like SearchDuplicates but stops promptly when
+// ctx is cancelled, returning the duplicates found so far and ctx.Err()
+func (df *DuplicateFinder) SearchDuplicatesContext(ctx context.Context, rootDir string, progressChan chan<- int) ([]Duplicate, error) {
+	return df.SearchRoots(ctx, []string{rootDir}, progressChan)
 }
 
-// processFile processes a single file and checks for duplicates
-func (df *DuplicateFinder) processFile(filePath string) error {
-	hash, err := df.calculateFileHash(filePath)
-	if err != nil {
-		return err
-	}
-
-	df.mu.Lock()
-	defer df.mu.Unlock()
-
-	if originalPath, exists := df.fileHashes[hash]; exists {
-		df.duplicates = append(df.duplicates, Duplicate{
-			Original:  originalPath,
-			Duplicate: filePath,
-		})
-	} else {
-		df.fileHashes[hash] = filePath
+// SearchRoots is like SearchDuplicatesContext but searches several root
+// directories, in order, as one tree
+func (df *DuplicateFinder) SearchRoots(ctx context.Context, roots []string, progressChan chan<- int) ([]Duplicate, error) {
+	// Verify root directories exist and do not overlap
+	if err := verifyRoots(roots); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-// processDirectory recursively processes a directory
-func (df *DuplicateFinder) processDirectory(dirPath string, progressChan chan<- int) error {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	// Reset state
+	if err := df.reset(roots); err != nil {
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		fullPath := filepath.Join(dirPath, entry.Name())
-
-		if entry.IsDir() {
-			if !df.isExcluded(fullPath) {
-				if err := df.processDirectory(fullPath, progressChan); err != nil {
-					// Log warning but continue processing
-					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-				}
+	for _, root := range roots {
+		err := df.activeWalker.Walk(root, func(file FileEntry) error {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
-		} else if !entry.IsDir() {
-			if err := df.processFile(fullPath); err != nil {
-				// Log warning but continue processing
-				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			file.Root = root
+			if df.admit(ctx, file, progressChan) {
+				df.advance(ctx, 0, candidate{file: file}, progressChan)
 			}
-			progressChan <- 1
+			return nil
+		})
+		if ctx.Err() != nil {
+			df.flushGroups()
+			return df.duplicates(), ctx.Err()
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
-}
+	df.finish(progressChan)
+	df.flushGroups()
 
-// SearchDuplicates finds duplicate files in the specified directory
-func (df *DuplicateFinder) SearchDuplicates(rootDir string, progressChan chan<- int) ([]Duplicate, error) {
-	// Verify root directory exists
-	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("directory does not exist: %s", rootDir)
-	}
-
-	// Reset state
-	df.fileHashes = make(map[string]string)
-	df.duplicates = make([]Duplicate, 0)
-
-	// Process directory
-	if err := df.processDirectory(rootDir, progressChan); err != nil {
-		return nil, err
-	}
-
-	return df.duplicates, nil
+	return df.duplicates(), nil
 }
 
 // GatherDuplicates groups duplicate files by their original file path
@@ -214,21 +218,50 @@ func GatherDuplicates(duplicates []Duplicate) map[string][]string {
 // GetDuplicateStats generates statistics about found duplicates
 func GetDuplicateStats(duplicates []Duplicate) DuplicateStats {
 	duplicateGroups := GatherDuplicates(duplicates)
-	
+
 	// Count unique originals
 	uniqueOriginals := len(duplicateGroups)
-	
+
 	// Count total duplicate files (originals + duplicates)
 	totalDuplicateFiles := len(duplicates) + uniqueOriginals
 
+	// Every duplicate beyond its original is wasted space
+	var wasted, reclaimable int64
+	groupWasted := make(map[string]int64, uniqueOriginals)
+	for _, dup := range duplicates {
+		wasted += dup.Size
+		reclaimable += dup.Allocated
+		groupWasted[dup.Original] += dup.Size
+	}
+
 	return DuplicateStats{
 		TotalDuplicates:     len(duplicates),
 		UniqueOriginals:     uniqueOriginals,
 		TotalDuplicateFiles: totalDuplicateFiles,
 		DuplicateGroups:     duplicateGroups,
+		WastedBytes:         wasted,
+		ReclaimableBytes:    reclaimable,
+		GroupWastedBytes:    groupWasted,
 	}
 }
 
+// GroupsByWaste returns the originals of stats' duplicate groups, the group
+// wasting the most space first. Ties are broken by path.
+func (s DuplicateStats) GroupsByWaste() []string {
+	originals := make([]string, 0, len(s.DuplicateGroups))
+	for original := range s.DuplicateGroups {
+		originals = append(originals, original)
+	}
+	sort.Slice(originals, func(i, j int) bool {
+		wi, wj := s.GroupWastedBytes[originals[i]], s.GroupWastedBytes[originals[j]]
+		if wi != wj {
+			return wi > wj
+		}
+		return originals[i] < originals[j]
+	})
+	return originals
+}
+
 // ValidateDirectory checks if a directory exists and is accessible
 func ValidateDirectory(dirPath string) bool {
 	info, err := os.Stat(dirPath)
@@ -237,7 +270,17 @@ func ValidateDirectory(dirPath string) bool {
 
 // GetSupportedAlgorithms returns the list of supported hash algorithms
 func GetSupportedAlgorithms() []HashAlgorithm {
-	return []HashAlgorithm{MD5, SHA1, SHA256, SHA512}
+	return []HashAlgorithm{MD5, SHA1, SHA256, SHA512, XXHash64, XXH3, BLAKE3, CRC32C}
+}
+
+// IsCryptographic reports whether the algorithm is a cryptographic hash
+func IsCryptographic(algorithm HashAlgorithm) bool {
+	switch algorithm {
+	case XXHash64, XXH3, CRC32C:
+		return false
+	default:
+		return true
+	}
 }
 
 // IsValidAlgorithm checks if the given algorithm is supported
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// excludeRule is one parsed exclusion pattern
+type excludeRule struct {
+	pattern  string
+	segments []string
+	negate   bool
+	anchored bool
+	dirOnly  bool
+}
+
+// ExcludeRules decides which files and directories a walk skips. Patterns use
+// gitignore syntax:
+//
+//   - A pattern without a slash matches a single path component at any depth,
+//     so "build" matches "src/build" but not "rebuild-notes"
+//   - A pattern containing a slash is anchored to the scan root; a leading
+//     slash anchors a single-component pattern
+//   - "*", "?" and "[...]" match within a component; "**" matches any number
+//     of components
+//   - A trailing slash restricts a pattern to directories
+//   - A leading "!" re-includes paths matched by an earlier pattern
+//
+// When several patterns match, the last one wins.
+type ExcludeRules struct {
+	dirRules  []excludeRule
+	fileRules []excludeRule
+}
+
+// ParseExcludeRules parses directory and file exclusion patterns. Directory
+// patterns only ever match directories and file patterns only ever match
+// files.
+func ParseExcludeRules(dirPatterns, filePatterns []string) (*ExcludeRules, error) {
+	rules := &ExcludeRules{}
+	for _, pattern := range dirPatterns {
+		rule, ok, err := parseExcludeRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rules.dirRules = append(rules.dirRules, rule)
+		}
+	}
+	for _, pattern := range filePatterns {
+		rule, ok, err := parseExcludeRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !rule.dirOnly {
+			rules.fileRules = append(rules.fileRules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// parseExcludeRule parses a single pattern. Blank patterns and comments are
+// skipped (ok is false).
+func parseExcludeRule(pattern string) (excludeRule, bool, error) {
+	rule := excludeRule{pattern: pattern}
+
+	p := strings.TrimSpace(pattern)
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule, false, nil
+	}
+
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if strings.Contains(p, "/") {
+		rule.anchored = true
+		p = strings.TrimPrefix(p, "/")
+	}
+	if p == "" {
+		return rule, false, nil
+	}
+
+	rule.segments = strings.Split(p, "/")
+	for _, segment := range rule.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return rule, false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	return rule, true, nil
+}
+
+// matches reports whether the rule matches a slash-separated path relative
+// to the scan root
+func (r excludeRule) matches(relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	if !r.anchored {
+		// Unanchored patterns match the last component at any depth
+		return matchSegments(r.segments, parts[len(parts)-1:])
+	}
+	return matchSegments(r.segments, parts)
+}
+
+// matchSegments matches pattern components against path components, with
+// "**" standing for any number of path components
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		// A trailing "**" matches everything inside, but not the directory itself
+		if len(pattern) == 1 {
+			return len(parts) > 0
+		}
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+// Excluded reports whether a path should be skipped. relPath is relative to
+// the scan root and uses forward slashes.
+func (r *ExcludeRules) Excluded(relPath string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+
+	rules := r.fileRules
+	if isDir {
+		rules = r.dirRules
+	}
+
+	excluded := false
+	for _, rule := range rules {
+		if rule.matches(relPath) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,57 @@
+package core
+
+import "testing"
+
+func TestExcludeRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		dirPatterns  []string
+		filePatterns []string
+		path         string
+		isDir        bool
+		want         bool
+	}{
+		{"component at root", []string{"build"}, nil, "build", true, true},
+		{"component at depth", []string{"build"}, nil, "src/build", true, true},
+		{"component is whole", []string{"build"}, nil, "rebuild-notes", true, false},
+		{"leading slash anchors", []string{"/build"}, nil, "src/build", true, false},
+		{"leading slash at root", []string{"/build"}, nil, "build", true, true},
+		{"slash anchors", []string{"docs/tmp"}, nil, "docs/tmp", true, true},
+		{"slash anchored elsewhere", []string{"docs/tmp"}, nil, "x/docs/tmp", true, false},
+		{"double star prefix", []string{"**/cache"}, nil, "a/b/cache", true, true},
+		{"double star prefix at root", []string{"**/cache"}, nil, "cache", true, true},
+		{"double star middle none", []string{"a/**/z"}, nil, "a/z", true, true},
+		{"double star middle many", []string{"a/**/z"}, nil, "a/b/c/z", true, true},
+		{"star in component", nil, []string{"*.log"}, "x/y.log", false, true},
+		{"star stays in component", nil, []string{"x*.log"}, "x/y.log", false, false},
+		{"question mark", nil, []string{"file?.txt"}, "file1.txt", false, true},
+		{"character class", nil, []string{"[ab].txt"}, "a.txt", false, true},
+		{"character class miss", nil, []string{"[ab].txt"}, "c.txt", false, false},
+		{"negation re-includes", nil, []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation leaves others", nil, []string{"*.log", "!keep.log"}, "a.log", false, true},
+		{"last match wins", nil, []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"directory-only file pattern dropped", nil, []string{"out/"}, "out", false, false},
+		{"directory patterns skip files", []string{"node_modules"}, nil, "node_modules", false, false},
+		{"file patterns skip directories", nil, []string{"*.log"}, "logs.log", true, false},
+		{"comment ignored", nil, []string{"#a.log"}, "#a.log", false, false},
+		{"blank ignored", []string{"", "  "}, nil, "anything", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseExcludeRules(tt.dirPatterns, tt.filePatterns)
+			if err != nil {
+				t.Fatalf("ParseExcludeRules: %v", err)
+			}
+			if got := rules.Excluded(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Excluded(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeRulesInvalid(t *testing.T) {
+	if _, err := ParseExcludeRules([]string{"[a"}, nil); err == nil {
+		t.Error("expected an error for an unterminated character class")
+	}
+}
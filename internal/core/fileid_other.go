@@ -0,0 +1,19 @@
+//go:build !unix
+
+package core
+
+import (
+	"os"
+)
+
+// FileIdentity returns the device and inode numbers backing a file. They are
+// not available on this platform, so ok is always false.
+func FileIdentity(info os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
+
+// LinkCount returns the number of hard links to a file. Links cannot be
+// detected on this platform, so every file counts as having one.
+func LinkCount(info os.FileInfo) uint64 {
+	return 1
+}
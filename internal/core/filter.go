@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FilterOptions selects which walked files take part in a search. Filters
+// only look at a file's name and stat information, so files they reject are
+// never opened. The zero value of each field disables that filter.
+type FilterOptions struct {
+	MinSize    int64     // Smallest size in bytes to consider
+	MaxSize    int64     // Largest size in bytes to consider
+	IncludeExt []string  // Only consider these extensions
+	ExcludeExt []string  // Never consider these extensions
+	Names      []string  // Only consider base names matching one of these globs
+	NewerThan  time.Time // Only consider files modified after this time
+	OlderThan  time.Time // Only consider files modified before this time
+}
+
+// IsZero reports whether no filter is enabled
+func (o FilterOptions) IsZero() bool {
+	return o.MinSize == 0 && o.MaxSize == 0 &&
+		len(o.IncludeExt) == 0 && len(o.ExcludeExt) == 0 && len(o.Names) == 0 &&
+		o.NewerThan.IsZero() && o.OlderThan.IsZero()
+}
+
+// Filters validates the options and returns a Filter for each enabled one
+func (o FilterOptions) Filters() ([]Filter, error) {
+	if o.MinSize < 0 || o.MaxSize < 0 {
+		return nil, fmt.Errorf("size limits must not be negative")
+	}
+	if o.MaxSize > 0 && o.MinSize > o.MaxSize {
+		return nil, fmt.Errorf("minimum size %d exceeds maximum size %d", o.MinSize, o.MaxSize)
+	}
+	if !o.NewerThan.IsZero() && !o.OlderThan.IsZero() && !o.NewerThan.Before(o.OlderThan) {
+		return nil, fmt.Errorf("no file can be both newer than %s and older than %s",
+			o.NewerThan.Format(time.RFC3339), o.OlderThan.Format(time.RFC3339))
+	}
+
+	var filters []Filter
+	if o.MinSize > 0 {
+		filters = append(filters, MinSizeFilter(o.MinSize))
+	}
+	if o.MaxSize > 0 {
+		filters = append(filters, MaxSizeFilter(o.MaxSize))
+	}
+	if len(o.IncludeExt) > 0 {
+		filters = append(filters, ExtensionFilter(o.IncludeExt, true))
+	}
+	if len(o.ExcludeExt) > 0 {
+		filters = append(filters, ExtensionFilter(o.ExcludeExt, false))
+	}
+	if len(o.Names) > 0 {
+		filter, err := NameFilter(o.Names)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if !o.NewerThan.IsZero() {
+		filters = append(filters, ModifiedAfterFilter(o.NewerThan))
+	}
+	if !o.OlderThan.IsZero() {
+		filters = append(filters, ModifiedBeforeFilter(o.OlderThan))
+	}
+	return filters, nil
+}
+
+// MinSizeFilter rejects files smaller than size bytes
+func MinSizeFilter(size int64) Filter {
+	return FilterFunc(func(file FileEntry) bool {
+		return file.Size >= size
+	})
+}
+
+// MaxSizeFilter rejects files larger than size bytes
+func MaxSizeFilter(size int64) Filter {
+	return FilterFunc(func(file FileEntry) bool {
+		return file.Size <= size
+	})
+}
+
+// NormalizeExtension lowercases an extension and strips its leading dot, so
+// ".JPG" and "jpg" compare equal
+func NormalizeExtension(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+// ExtensionFilter keeps only files with one of the given extensions when
+// include is true, and rejects them when it is false. Extensions are matched
+// case-insensitively and may span several dots, as in "tar.gz".
+func ExtensionFilter(exts []string, include bool) Filter {
+	suffixes := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		if ext = NormalizeExtension(ext); ext != "" {
+			suffixes = append(suffixes, "."+ext)
+		}
+	}
+
+	return FilterFunc(func(file FileEntry) bool {
+		name := strings.ToLower(filepath.Base(file.Path))
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(name, suffix) {
+				return include
+			}
+		}
+		return !include
+	})
+}
+
+// NameFilter keeps only files whose base name matches at least one of the
+// given glob patterns
+func NameFilter(patterns []string) (Filter, error) {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+	}
+
+	return FilterFunc(func(file FileEntry) bool {
+		name := filepath.Base(file.Path)
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// ModifiedAfterFilter rejects files last modified at or before t
+func ModifiedAfterFilter(t time.Time) Filter {
+	bound := t.UnixNano()
+	return FilterFunc(func(file FileEntry) bool {
+		return file.ModTime > bound
+	})
+}
+
+// ModifiedBeforeFilter rejects files last modified at or after t
+func ModifiedBeforeFilter(t time.Time) Filter {
+	bound := t.UnixNano()
+	return FilterFunc(func(file FileEntry) bool {
+		return file.ModTime < bound
+	})
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"sort"
+	"sync"
+)
+
+// firstSeenGrouper treats the first file hashed with a given digest as the
+// original and every later file with that digest as its duplicate. With
+// verify set, a hash can hold several originals, one per distinct content.
+type firstSeenGrouper struct {
+	verify     bool
+	fileHashes map[string][]FileEntry // The originals recorded for each hash
+	byHash     map[string][]Duplicate
+	collisions []HashCollision
+	mu         sync.Mutex
+}
+
+// NewGrouper returns the default Grouper. With verify set, every duplicate is
+// confirmed byte for byte against its original. A file matching none of the
+// originals recorded for its hash is recorded as a hash collision and becomes
+// an original itself, so later copies of it are still grouped with it.
+func NewGrouper(verify bool) Grouper {
+	g := &firstSeenGrouper{verify: verify}
+	g.Reset()
+	return g
+}
+
+// Reset clears all recorded files
+func (g *firstSeenGrouper) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.fileHashes = make(map[string][]FileEntry)
+	g.byHash = make(map[string][]Duplicate)
+	g.collisions = nil
+}
+
+// Add records a fully hashed file as an original or a duplicate
+func (g *firstSeenGrouper) Add(file FileEntry, hash string) error {
+	g.mu.Lock()
+	originals := g.fileHashes[hash]
+	if len(originals) == 0 {
+		g.fileHashes[hash] = []FileEntry{file}
+	}
+	g.mu.Unlock()
+
+	if len(originals) == 0 {
+		return nil
+	}
+	if !g.verify {
+		g.addDuplicate(originals[0], file, hash)
+		return nil
+	}
+
+	// Compare outside the lock so concurrent callers keep going meanwhile,
+	// then check again for originals recorded in the meantime
+	checked := 0
+	for {
+		for _, original := range originals[checked:] {
+			equal, err := FilesEqual(original.Path, file.Path)
+			if err != nil {
+				return err
+			}
+			if equal {
+				g.addDuplicate(original, file, hash)
+				return nil
+			}
+		}
+		checked = len(originals)
+
+		g.mu.Lock()
+		originals = g.fileHashes[hash]
+		if len(originals) == checked {
+			g.fileHashes[hash] = append(originals, file)
+			g.collisions = append(g.collisions, HashCollision{
+				Original:  originals[0].Path,
+				Candidate: file.Path,
+				Hash:      hash,
+			})
+			g.mu.Unlock()
+			return nil
+		}
+		g.mu.Unlock()
+	}
+}
+
+// addDuplicate records file as a duplicate of original
+func (g *firstSeenGrouper) addDuplicate(original, file FileEntry, hash string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.byHash[hash] = append(g.byHash[hash], Duplicate{
+		Original:          original.Path,
+		Duplicate:         file.Path,
+		OriginalRoot:      original.Root,
+		DuplicateRoot:     file.Root,
+		Hash:              hash,
+		Size:              file.Size,
+		Allocated:         file.Allocated,
+		OriginalAllocated: original.Allocated,
+		OriginalModTime:   original.ModTime,
+		DuplicateModTime:  file.ModTime,
+	})
+}
+
+// Duplicates returns the duplicate pairs recorded so far and not taken,
+// ordered by hash
+func (g *firstSeenGrouper) Duplicates() []Duplicate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	hashes := make([]string, 0, len(g.byHash))
+	for hash := range g.byHash {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	duplicates := make([]Duplicate, 0)
+	for _, hash := range hashes {
+		duplicates = append(duplicates, g.byHash[hash]...)
+	}
+	return duplicates
+}
+
+// TakeDuplicates returns the duplicate pairs recorded for one hash and
+// forgets them, along with the originals they share
+func (g *firstSeenGrouper) TakeDuplicates(hash string) []Duplicate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group := g.byHash[hash]
+	delete(g.byHash, hash)
+	delete(g.fileHashes, hash)
+	return group
+}
+
+// Collisions returns the hash collisions recorded so far
+func (g *firstSeenGrouper) Collisions() []HashCollision {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.collisions
+}
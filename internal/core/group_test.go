@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGrouperVerifyGroupsCollidingCopies(t *testing.T) {
+	dir := t.TempDir()
+	var files []FileEntry
+	for _, f := range []struct{ name, content string }{
+		{"a", "first content"},
+		{"b", "other content"},
+		{"c", "other content"},
+		{"d", "first content"},
+	} {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, FileEntry{Path: path, Size: int64(len(f.content))})
+	}
+
+	// Every file gets the same hash, as if the algorithm collided
+	g := NewGrouper(true)
+	for _, file := range files {
+		if err := g.Add(file, "collision"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{files[3].Path: files[0].Path, files[2].Path: files[1].Path}
+	duplicates := g.Duplicates()
+	if len(duplicates) != len(want) {
+		t.Fatalf("Duplicates = %v, want %d pairs", duplicates, len(want))
+	}
+	for _, dup := range duplicates {
+		if want[dup.Duplicate] != dup.Original {
+			t.Errorf("%s grouped with %s, want %s", dup.Duplicate, dup.Original, want[dup.Duplicate])
+		}
+	}
+
+	collisions := g.Collisions()
+	if len(collisions) != 1 || collisions[0].Original != files[0].Path || collisions[0].Candidate != files[1].Path {
+		t.Errorf("Collisions = %v, want %s colliding with %s", collisions, files[1].Path, files[0].Path)
+	}
+}
+
+// constantHasher gives every file the same hash
+type constantHasher struct{}
+
+func (constantHasher) Hash(ctx context.Context, file FileEntry) (string, error) {
+	return "collision", nil
+}
+
+func TestGroupHandlerSplitsCollidingGroups(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a": "first", "b": "other", "c": "other", "d": "first"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	finder := NewDuplicateFinder(MD5, nil)
+	finder.SetHasher(constantHasher{})
+	finder.SetSampleSize(0)
+	finder.SetVerify(true)
+	var groups [][]Duplicate
+	finder.SetGroupHandler(func(group []Duplicate) {
+		groups = append(groups, group)
+	})
+	if _, err := finder.SearchDuplicates(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("handed out %d groups, want 2: %v", len(groups), groups)
+	}
+	for _, group := range groups {
+		if len(group) != 1 {
+			t.Errorf("group of %s holds %d duplicates, want 1", group[0].Original, len(group))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/xxh3"
+	"lukechampine.com/blake3"
+)
+
+// castagnoliTable is the CRC32C polynomial table, shared by all CRC32C hashers
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// NewHash returns the appropriate hash.Hash for the given algorithm
+func NewHash(algorithm HashAlgorithm) hash.Hash {
+	switch algorithm {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA256:
+		return sha256.New()
+	case SHA512:
+		return sha512.New()
+	case XXHash64:
+		return xxhash.New()
+	case XXH3:
+		return xxh3.New()
+	case BLAKE3:
+		return blake3.New(32, nil)
+	case CRC32C:
+		return crc32.New(castagnoliTable)
+	default:
+		return md5.New() // Default to MD5
+	}
+}
+
+// contextReader stops reading as soon as its context is cancelled, so that
+// hashing a large file can be abandoned part-way through
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// calculateFileHash calculates the hash of a file's entire contents
+func calculateFileHash(ctx context.Context, algorithm HashAlgorithm, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := NewHash(algorithm)
+	if _, err := io.Copy(hash, contextReader{ctx: ctx, r: file}); err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// calculatePartialHash hashes the first and last sampleSize bytes of a file
+func calculatePartialHash(ctx context.Context, algorithm HashAlgorithm, filePath string, size, sampleSize int64) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := NewHash(algorithm)
+	head := min(size, sampleSize)
+	if _, err := io.Copy(hash, contextReader{ctx: ctx, r: io.NewSectionReader(file, 0, head)}); err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	if tailStart := max(head, size-sampleSize); tailStart < size {
+		if _, err := io.Copy(hash, contextReader{ctx: ctx, r: io.NewSectionReader(file, tailStart, size-tailStart)}); err != nil {
+			return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// Names of the built-in narrowing stages, as reported in DuplicateStats
+const (
+	StageSize    = "size"
+	StagePartial = "partial"
+)
+
+// sizeStage groups files by their size
+type sizeStage struct{}
+
+// SizeStage returns the stage that eliminates files whose size is unique
+func SizeStage() Stage {
+	return sizeStage{}
+}
+
+func (sizeStage) Name() string { return StageSize }
+
+func (sizeStage) Key(ctx context.Context, file FileEntry) (string, error) {
+	return strconv.FormatInt(file.Size, 10), nil
+}
+
+// partialHashStage groups files by a hash of their head and tail
+type partialHashStage struct {
+	algorithm  HashAlgorithm
+	sampleSize int64
+}
+
+// NewPartialHashStage returns the stage that eliminates files whose first and
+// last sampleSize bytes match no other file
+func NewPartialHashStage(algorithm HashAlgorithm, sampleSize int64) Stage {
+	return partialHashStage{algorithm: algorithm, sampleSize: sampleSize}
+}
+
+func (s partialHashStage) Name() string { return StagePartial }
+
+func (s partialHashStage) Key(ctx context.Context, file FileEntry) (string, error) {
+	return calculatePartialHash(ctx, s.algorithm, file.Path, file.Size, s.sampleSize)
+}
+
+// contentHasher computes full-content hashes, consulting an optional
+// persistent cache first
+type contentHasher struct {
+	algorithm HashAlgorithm
+	cache     HashCache
+	cacheHits atomic.Int64
+}
+
+// NewContentHasher returns a Hasher that hashes a file's entire contents. A
+// non-nil cache is consulted before hashing and updated afterwards.
+func NewContentHasher(algorithm HashAlgorithm, cache HashCache) Hasher {
+	return &contentHasher{algorithm: algorithm, cache: cache}
+}
+
+// Hash returns the full-content hash of a file, reusing the cached digest
+// when the file is unchanged since it was last hashed
+func (h *contentHasher) Hash(ctx context.Context, file FileEntry) (string, error) {
+	if h.cache == nil {
+		return calculateFileHash(ctx, h.algorithm, file.Path)
+	}
+	path, err := filepath.Abs(file.Path)
+	if err != nil {
+		return calculateFileHash(ctx, h.algorithm, file.Path)
+	}
+
+	key := CacheKey{
+		Path:      path,
+		Size:      file.Size,
+		ModTime:   file.ModTime,
+		Inode:     file.Inode,
+		Algorithm: h.algorithm,
+	}
+	if hash, ok := h.cache.Lookup(key); ok {
+		h.cacheHits.Add(1)
+		return hash, nil
+	}
+
+	hash, err := calculateFileHash(ctx, h.algorithm, file.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := h.cache.Store(key, hash); err != nil {
+		// A cache failure should never fail the scan
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache hash for %s: %v\n", file.Path, err)
+	}
+
+	return hash, nil
+}
+
+// CacheHits returns how many hashes were served from the cache
+func (h *contentHasher) CacheHits() int {
+	return int(h.cacheHits.Load())
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mapCache is an in-memory HashCache
+type mapCache map[CacheKey]string
+
+func (c mapCache) Lookup(key CacheKey) (string, bool) {
+	hash, ok := c[key]
+	return hash, ok
+}
+
+func (c mapCache) Store(key CacheKey, hash string) error {
+	c[key] = hash
+	return nil
+}
+
+func TestContentHasherAbsoluteCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "sub", "file")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cache := mapCache{}
+	hasher := NewContentHasher(MD5, cache).(*contentHasher)
+	// The same file, walked from two working directories
+	for _, walked := range []struct{ cwd, path string }{
+		{dir, filepath.Join("sub", "file")},
+		{filepath.Join(dir, "sub"), "file"},
+	} {
+		if err := os.Chdir(walked.cwd); err != nil {
+			t.Fatal(err)
+		}
+		file := FileEntry{Path: walked.path, Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		if _, err := hasher.Hash(context.Background(), file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(cache) != 1 {
+		t.Fatalf("cache holds %d entries, want 1: %v", len(cache), cache)
+	}
+	for key := range cache {
+		if want, _ := filepath.Abs(path); key.Path != want {
+			t.Errorf("cached path = %q, want %q", key.Path, want)
+		}
+	}
+	if hasher.CacheHits() != 1 {
+		t.Errorf("cache hits = %d, want 1", hasher.CacheHits())
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+
+	for _, algo := range GetSupportedAlgorithms() {
+		b.Run(string(algo), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				hash := NewHash(algo)
+				hash.Write(data)
+				hash.Sum(nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the ignore files honored by a gitignore-aware walk. Within a
+// directory, .clonespotterignore takes precedence over .gitignore.
+const (
+	gitignoreFile  = ".gitignore"
+	toolIgnoreFile = ".clonespotterignore"
+	gitDir         = ".git"
+	gitInfoExclude = "info/exclude"
+)
+
+// ignoreLayer holds the patterns read from one ignore file. base is the
+// directory the patterns are relative to, as a slash-separated path relative
+// to the top of the walk.
+type ignoreLayer struct {
+	base  string
+	rules []excludeRule
+	git   bool // read from a git file, and so scoped to one repository
+}
+
+// match reports whether the layer has an opinion on a path and, if so,
+// whether it ignores it. The last matching pattern wins.
+func (l ignoreLayer) match(topRel string, isDir bool) (ignored, matched bool) {
+	rel := topRel
+	if l.base != "" {
+		if !strings.HasPrefix(topRel, l.base+"/") {
+			return false, false
+		}
+		rel = topRel[len(l.base)+1:]
+	}
+
+	for _, rule := range l.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			ignored, matched = !rule.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// ignoreStack is the set of ignore layers in effect for a directory, lowest
+// precedence first. Following git, a deeper ignore file overrides a shallower
+// one and .git/info/exclude has the lowest precedence of all.
+type ignoreStack []ignoreLayer
+
+// ignored reports whether a path, relative to the top of the walk, is ignored
+func (s ignoreStack) ignored(topRel string, isDir bool) bool {
+	for i := len(s) - 1; i >= 0; i-- {
+		if ignored, matched := s[i].match(topRel, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// enter returns the stack in effect inside a directory. dirPath is the
+// directory on disk and base its path relative to the top of the walk. A
+// directory holding a .git entry starts a new repository, so the git layers
+// of any enclosing repository no longer apply.
+func (s ignoreStack) enter(dirPath, base string) ignoreStack {
+	next := s
+	if _, err := os.Lstat(filepath.Join(dirPath, gitDir)); err == nil {
+		next = ignoreStack{}
+		if layer, ok := readIgnoreFile(filepath.Join(dirPath, gitDir, filepath.FromSlash(gitInfoExclude)), base, true); ok {
+			next = append(next, layer)
+		}
+		for _, layer := range s {
+			if !layer.git {
+				next = append(next, layer)
+			}
+		}
+	}
+
+	for _, name := range []string{gitignoreFile, toolIgnoreFile} {
+		if layer, ok := readIgnoreFile(filepath.Join(dirPath, name), base, name == gitignoreFile); ok {
+			// Copy so sibling directories never share a backing array
+			next = append(next[:len(next):len(next)], layer)
+		}
+	}
+	return next
+}
+
+// readIgnoreFile parses an ignore file, skipping invalid patterns with a
+// warning. ok is false if the file does not exist or holds no patterns.
+func readIgnoreFile(filePath, base string, git bool) (ignoreLayer, bool) {
+	layer := ignoreLayer{base: base, git: git}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			// Log warning but continue processing
+			fmt.Fprintf(os.Stderr, "Warning: failed to read ignore file %s: %v\n", filePath, err)
+		}
+		return layer, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		rule, ok, err := parseExcludeRule(strings.TrimSuffix(scanner.Text(), "\r"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", filePath, err)
+			continue
+		}
+		if ok {
+			layer.rules = append(layer.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read ignore file %s: %v\n", filePath, err)
+	}
+
+	return layer, len(layer.rules) > 0
+}
+
+// rootIgnoreStack builds the stack in effect at the root of a walk. When the
+// root lies inside a git repository, the ignore files of the directories
+// between the repository's top level and the root apply as well. It returns
+// the stack and the root's path relative to the top level.
+func rootIgnoreStack(rootDir string) (ignoreStack, string) {
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return ignoreStack{}.enter(rootDir, ""), ""
+	}
+
+	// Find the enclosing repository, if any
+	top := absRoot
+	for {
+		if _, err := os.Lstat(filepath.Join(top, gitDir)); err == nil {
+			break
+		}
+		parent := filepath.Dir(top)
+		if parent == top {
+			top = absRoot
+			break
+		}
+		top = parent
+	}
+
+	rel, err := filepath.Rel(top, absRoot)
+	if err != nil || rel == "." {
+		return ignoreStack{}.enter(absRoot, ""), ""
+	}
+	rootRel := filepath.ToSlash(rel)
+
+	stack := ignoreStack{}.enter(top, "")
+	dirPath, base := top, ""
+	for _, name := range strings.Split(rootRel, "/") {
+		dirPath = filepath.Join(dirPath, name)
+		base = path.Join(base, name)
+		stack = stack.enter(dirPath, base)
+	}
+	return stack, rootRel
+}
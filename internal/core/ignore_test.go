@@ -0,0 +1,62 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnorePrecedence(t *testing.T) {
+	top := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(top, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".git/info/exclude", "*.tmp\n")
+	write(".gitignore", "*.log\n!keep.tmp\n")
+	write(".clonespotterignore", "!important.log\n")
+	write("sub/.gitignore", "!*.log\n")
+	write("nested/.git/HEAD", "")
+	write("nested/.gitignore", "*.bak\n")
+
+	root := ignoreStack{}.enter(top, "")
+	stacks := map[string]ignoreStack{
+		"":       root,
+		"sub":    root.enter(filepath.Join(top, "sub"), "sub"),
+		"nested": root.enter(filepath.Join(top, "nested"), "nested"),
+	}
+
+	tests := []struct {
+		name  string
+		dir   string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"info/exclude applies", "", "a.tmp", false, true},
+		{".gitignore overrides info/exclude", "", "keep.tmp", false, false},
+		{".gitignore applies", "", "a.log", false, true},
+		{".clonespotterignore overrides .gitignore", "", "important.log", false, false},
+		{"unmatched path kept", "", "a.txt", false, false},
+		{"parent layers apply at depth", "sub", "sub/x.tmp", false, true},
+		{"enclosing .gitignore stops at a new repository", "nested", "nested/x.log", false, false},
+		{"deeper .gitignore overrides shallower", "sub", "sub/b.log", false, false},
+		{"new repository drops enclosing git layers", "nested", "nested/c.tmp", false, false},
+		{"new repository keeps its own .gitignore", "nested", "nested/d.bak", false, true},
+		{".clonespotterignore crosses repositories", "nested", "nested/important.log", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stacks[tt.dir].ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
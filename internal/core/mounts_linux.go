@@ -0,0 +1,86 @@
+//go:build linux
+
+package core
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mountInfoPath lists the mounts visible to this process
+const mountInfoPath = "/proc/self/mountinfo"
+
+// pseudoFilesystems are the filesystem types never worth scanning
+var pseudoFilesystems = map[string]bool{
+	"proc":        true,
+	"sysfs":       true,
+	"devtmpfs":    true,
+	"devpts":      true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"securityfs":  true,
+	"debugfs":     true,
+	"tracefs":     true,
+	"pstore":      true,
+	"bpf":         true,
+	"configfs":    true,
+	"fusectl":     true,
+	"mqueue":      true,
+	"binfmt_misc": true,
+	"efivarfs":    true,
+	"selinuxfs":   true,
+}
+
+// PseudoMounts returns the mount points of pseudo filesystems, whose files
+// describe the kernel rather than hold data and can block or never end when
+// read. They are read from the process's mount table; an unreadable table
+// yields none.
+func PseudoMounts() map[string]bool {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	mounts := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Fields: id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		for i := 5; i < len(fields)-1; i++ {
+			if fields[i] == "-" {
+				if pseudoFilesystems[fields[i+1]] {
+					mounts[unescapeMountPath(fields[4])] = true
+				}
+				break
+			}
+		}
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes (such as \040 for a space) the
+// kernel uses in mount points
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
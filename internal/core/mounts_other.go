@@ -0,0 +1,9 @@
+//go:build !linux
+
+package core
+
+// PseudoMounts returns the mount points of pseudo filesystems. They are only
+// detected on Linux, so this returns none.
+func PseudoMounts() map[string]bool {
+	return nil
+}
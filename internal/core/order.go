@@ -0,0 +1,147 @@
+package core
+
+import (
+	"sort"
+)
+
+// OriginalRule decides which file of a duplicate group is reported as the
+// original. Every rule breaks ties by path, so the choice never depends on
+// walk order or on how concurrent workers were scheduled.
+type OriginalRule string
+
+const (
+	// OriginalPath picks the lexicographically first path
+	OriginalPath OriginalRule = "path"
+	// OriginalOldest picks the file modified longest ago
+	OriginalOldest OriginalRule = "oldest"
+	// OriginalShortest picks the file with the shortest path
+	OriginalShortest OriginalRule = "shortest"
+	// OriginalRoot picks a file under the root searched first, so the root
+	// listed first is the preferred home of originals
+	OriginalRoot OriginalRule = "root"
+)
+
+// DefaultOriginalRule is the rule used unless another is set
+const DefaultOriginalRule = OriginalPath
+
+// GetOriginalRules returns the supported original-selection rules
+func GetOriginalRules() []OriginalRule {
+	return []OriginalRule{OriginalPath, OriginalOldest, OriginalShortest, OriginalRoot}
+}
+
+// IsValidOriginalRule checks if the given rule is supported
+func IsValidOriginalRule(rule string) bool {
+	for _, supported := range GetOriginalRules() {
+		if OriginalRule(rule) == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMember is a file of a duplicate group, with what the rules and the
+// re-paired duplicates need to know about it
+type groupMember struct {
+	path      string
+	root      string
+	modTime   int64
+	allocated int64
+}
+
+// ArrangeDuplicates picks the original of every duplicate group by rule and
+// returns the groups' pairs in a canonical order: groups sorted by original
+// path, and each group's duplicates sorted by path. roots are the searched
+// roots in the order given, which OriginalRoot ranks files by.
+func ArrangeDuplicates(duplicates []Duplicate, rule OriginalRule, roots []string) []Duplicate {
+	rootRank := make(map[string]int, len(roots))
+	for i, root := range roots {
+		if _, exists := rootRank[root]; !exists {
+			rootRank[root] = i
+		}
+	}
+
+	// Rebuild the groups, remembering each one's shared content
+	type group struct {
+		hash    string
+		size    int64
+		members []groupMember
+	}
+	var groups []*group
+	byOriginal := make(map[string]*group)
+	for _, dup := range duplicates {
+		g, exists := byOriginal[dup.Original]
+		if !exists {
+			g = &group{hash: dup.Hash, size: dup.Size}
+			g.members = append(g.members, groupMember{
+				path:      dup.Original,
+				root:      dup.OriginalRoot,
+				modTime:   dup.OriginalModTime,
+				allocated: dup.OriginalAllocated,
+			})
+			byOriginal[dup.Original] = g
+			groups = append(groups, g)
+		}
+		g.members = append(g.members, groupMember{
+			path:      dup.Duplicate,
+			root:      dup.DuplicateRoot,
+			modTime:   dup.DuplicateModTime,
+			allocated: dup.Allocated,
+		})
+	}
+
+	less := func(a, b groupMember) bool {
+		switch rule {
+		case OriginalOldest:
+			if a.modTime != b.modTime {
+				return a.modTime < b.modTime
+			}
+		case OriginalShortest:
+			if len(a.path) != len(b.path) {
+				return len(a.path) < len(b.path)
+			}
+		case OriginalRoot:
+			if rootRank[a.root] != rootRank[b.root] {
+				return rootRank[a.root] < rootRank[b.root]
+			}
+		}
+		return a.path < b.path
+	}
+
+	for _, g := range groups {
+		// Move the chosen original to the front and sort the rest by path
+		best := 0
+		for i := range g.members {
+			if less(g.members[i], g.members[best]) {
+				best = i
+			}
+		}
+		g.members[0], g.members[best] = g.members[best], g.members[0]
+		rest := g.members[1:]
+		sort.Slice(rest, func(i, j int) bool {
+			return rest[i].path < rest[j].path
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].members[0].path < groups[j].members[0].path
+	})
+
+	arranged := make([]Duplicate, 0, len(duplicates))
+	for _, g := range groups {
+		original := g.members[0]
+		for _, member := range g.members[1:] {
+			arranged = append(arranged, Duplicate{
+				Original:          original.path,
+				Duplicate:         member.path,
+				OriginalRoot:      original.root,
+				DuplicateRoot:     member.root,
+				Hash:              g.hash,
+				Size:              g.size,
+				Allocated:         member.allocated,
+				OriginalAllocated: original.allocated,
+				OriginalModTime:   original.modTime,
+				DuplicateModTime:  member.modTime,
+			})
+		}
+	}
+	return arranged
+}
@@ -0,0 +1,680 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scanner finds duplicate files beneath a root directory. DuplicateFinder and
+// ConcurrentDuplicateFinder are the built-in implementations; both are built
+// from the same walk, filter, stage, hash and grouping components.
+//
+// SearchDuplicatesContext stops promptly once ctx is cancelled and returns
+// the duplicates found so far together with the context's error. SearchRoots
+// does the same for several root directories searched as one tree, tagging
+// every duplicate with the roots its files were found under.
+type Scanner interface {
+	SearchDuplicates(rootDir string, progressChan chan<- int) ([]Duplicate, error)
+	SearchDuplicatesContext(ctx context.Context, rootDir string, progressChan chan<- int) ([]Duplicate, error)
+	SearchRoots(ctx context.Context, roots []string, progressChan chan<- int) ([]Duplicate, error)
+	Stats() DuplicateStats
+	Collisions() []HashCollision
+	EmptyFiles() []string
+	HardLinks() map[string][]string
+	Symlinks() []Symlink
+	Errors() []FileError
+}
+
+// FileEntry is a file discovered by a Walker, along with the stat
+// information the pipeline needs
+type FileEntry struct {
+	Path      string
+	Root      string // The root directory the file was found under
+	Size      int64
+	Allocated int64 // Bytes of disk space allocated to the file
+	ModTime   int64 // Unix nanoseconds
+	Device    uint64
+	Inode     uint64
+	Links     uint64 // Number of hard links to the file
+
+	// SymlinkTarget is set for symbolic links the walker reports rather than
+	// follows; the pipeline lists them instead of hashing them
+	SymlinkTarget string
+}
+
+// Symlink is a symbolic link found, but not followed, by a search
+type Symlink struct {
+	Path   string `json:"path"`
+	Target string `json:"target"`
+}
+
+// FileError is a file a search could not read, and why. The file is left
+// out of the results.
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// NewFileEntry builds a FileEntry from a file's stat information
+func NewFileEntry(path string, info os.FileInfo) FileEntry {
+	dev, ino, _ := FileIdentity(info)
+	return FileEntry{
+		Path:      path,
+		Size:      info.Size(),
+		Allocated: AllocatedSize(info),
+		ModTime:   info.ModTime().UnixNano(),
+		Device:    dev,
+		Inode:     ino,
+		Links:     LinkCount(info),
+	}
+}
+
+// fileID identifies a file on disk independently of the path it was found at
+type fileID struct {
+	dev, ino uint64
+}
+
+// Walker discovers the files beneath a root directory and passes each one to
+// visit. Returning an error from visit stops the walk.
+type Walker interface {
+	Walk(rootDir string, visit func(file FileEntry) error) error
+}
+
+// Filter decides whether a walked file takes part in the search
+type Filter interface {
+	Include(file FileEntry) bool
+}
+
+// FilterFunc adapts an ordinary function to the Filter interface
+type FilterFunc func(file FileEntry) bool
+
+// Include calls f(file)
+func (f FilterFunc) Include(file FileEntry) bool {
+	return f(file)
+}
+
+// Stage narrows down duplicate candidates by computing a key for each file.
+// A file whose key matches no other file (among those that passed every
+// earlier stage) cannot have a duplicate and is eliminated.
+type Stage interface {
+	Name() string
+	Key(ctx context.Context, file FileEntry) (string, error)
+}
+
+// Hasher computes the digest that decides whether two candidates are duplicates
+type Hasher interface {
+	Hash(ctx context.Context, file FileEntry) (string, error)
+}
+
+// Grouper collects fully hashed files into originals and duplicates. It
+// must be safe for concurrent use.
+type Grouper interface {
+	Reset()
+	Add(file FileEntry, hash string) error
+	Duplicates() []Duplicate
+	Collisions() []HashCollision
+}
+
+// candidate is a file moving through the narrowing stages. Its key
+// accumulates the key of every stage it has passed, so later stages only
+// compare files that matched at every earlier one.
+type candidate struct {
+	file FileEntry
+	key  string
+	// bucket is the key after the first stage. Files can only have the same
+	// content as files in the same bucket.
+	bucket string
+}
+
+// candidateBucket tracks how many files share a pipeline key, holding on to
+// the first one until a second arrives and proves it worth promoting
+type candidateBucket struct {
+	first candidate
+	count int
+}
+
+// add records a candidate in the bucket and returns the candidates that
+// should move on to the next stage: none for the first one, both once a
+// second one arrives, and just the new one after that
+func (b *candidateBucket) add(c candidate) []candidate {
+	b.count++
+	switch b.count {
+	case 1:
+		b.first = c
+		return nil
+	case 2:
+		return []candidate{b.first, c}
+	default:
+		return []candidate{c}
+	}
+}
+
+// reportProgress signals that one more file has been fully handled
+func reportProgress(progressChan chan<- int) {
+	if progressChan != nil {
+		progressChan <- 1
+	}
+}
+
+// pipeline holds the configuration, components and counters shared by the
+// Scanner implementations, which embed it. Components left unset are
+// replaced with the defaults built from the configuration when a search
+// starts.
+type pipeline struct {
+	algorithm     HashAlgorithm
+	excludedDirs  []string
+	excludedFiles []string
+	sampleSize    int64
+	verify        bool
+	cache         HashCache
+	respectIgnore bool
+	emptyPolicy   EmptyFilePolicy
+	symlinkPolicy SymlinkPolicy
+	oneFileSystem bool
+	originalRule  OriginalRule
+	groupHandler  GroupHandler
+
+	walker  Walker
+	filters []Filter
+	stages  []Stage
+	hasher  Hasher
+	grouper Grouper
+
+	// Per-search state
+	roots         []string
+	activeWalker  Walker
+	activeStages  []Stage
+	activeHasher  Hasher
+	activeGrouper Grouper
+	buckets       []map[string]*candidateBucket
+	filesScanned  int
+	filesFiltered int
+	emptyFiles    []string
+	emptyCount    int
+	linkTargets   map[fileID]string
+	hardLinks     map[string][]string
+	linkTotals    map[string]uint64 // Link count of each first link found
+	linkCount     int
+	symlinks      []Symlink
+	fileErrors    []FileError
+	unreadable    map[int64][]string // Paths of fileErrors, by size
+	filesHashed   int
+	eliminated    map[string]int
+	mu            sync.Mutex
+
+	// Group handler state: the first-stage buckets in the order they are
+	// handed out, how many candidates of each are still queued, the hashes
+	// found in each and the totals of the groups handed out
+	bucketKeys   []string
+	nextBucket   int
+	pending      map[string]int
+	bucketHashes map[string][]string
+	hashSeen     map[string]bool
+	handedOut    DuplicateStats
+	emitMu       sync.Mutex
+}
+
+// newPipeline creates a pipeline with the default configuration
+func newPipeline(algorithm HashAlgorithm, excludedDirs []string) pipeline {
+	return pipeline{
+		algorithm:     algorithm,
+		excludedDirs:  excludedDirs,
+		sampleSize:    DefaultSampleSize,
+		emptyPolicy:   DefaultEmptyFilePolicy,
+		symlinkPolicy: DefaultSymlinkPolicy,
+		originalRule:  DefaultOriginalRule,
+	}
+}
+
+// SetSampleSize sets how many bytes are hashed from the head and the tail of
+// each candidate during the partial-hash stage. A size of zero disables the
+// stage so every same-size candidate is fully hashed.
+func (p *pipeline) SetSampleSize(size int64) {
+	if size < 0 {
+		size = 0
+	}
+	p.sampleSize = size
+}
+
+// SetVerify enables byte-for-byte comparison of every duplicate candidate
+// against its original before it is reported. Candidates whose contents
+// differ are recorded as hash collisions instead of duplicates.
+func (p *pipeline) SetVerify(verify bool) {
+	p.verify = verify
+}
+
+// SetCache sets the persistent cache consulted before a file is fully
+// hashed. A nil cache disables caching.
+func (p *pipeline) SetCache(cache HashCache) {
+	p.cache = cache
+}
+
+// SetExcludedFiles sets the patterns of files to skip. Directory patterns are
+// passed to the constructor; see ExcludeRules for the pattern syntax.
+func (p *pipeline) SetExcludedFiles(patterns []string) {
+	p.excludedFiles = patterns
+}
+
+// SetRespectIgnoreFiles makes the default walker honor .gitignore,
+// .git/info/exclude and .clonespotterignore files
+func (p *pipeline) SetRespectIgnoreFiles(respect bool) {
+	p.respectIgnore = respect
+}
+
+// SetEmptyFilePolicy sets how zero-length files are handled. With EmptyIgnore
+// and EmptyReport they are set aside before any stage runs; only EmptyReport
+// keeps their paths for EmptyFiles.
+func (p *pipeline) SetEmptyFilePolicy(policy EmptyFilePolicy) {
+	p.emptyPolicy = policy
+}
+
+// SetSymlinkPolicy sets how the default walker handles symbolic links
+func (p *pipeline) SetSymlinkPolicy(policy SymlinkPolicy) {
+	p.symlinkPolicy = policy
+}
+
+// SetOneFileSystem keeps the default walker on the filesystem holding the
+// root directory
+func (p *pipeline) SetOneFileSystem(one bool) {
+	p.oneFileSystem = one
+}
+
+// SetOriginalRule sets how the original of each duplicate group is chosen
+func (p *pipeline) SetOriginalRule(rule OriginalRule) {
+	p.originalRule = rule
+}
+
+// SetWalker replaces the default directory walker
+func (p *pipeline) SetWalker(walker Walker) {
+	p.walker = walker
+}
+
+// AddFilter adds a filter that every walked file must pass
+func (p *pipeline) AddFilter(filter Filter) {
+	p.filters = append(p.filters, filter)
+}
+
+// SetStages replaces the default size and partial-hash narrowing stages
+func (p *pipeline) SetStages(stages ...Stage) {
+	p.stages = stages
+}
+
+// SetHasher replaces the default full-content hasher
+func (p *pipeline) SetHasher(hasher Hasher) {
+	p.hasher = hasher
+}
+
+// SetGrouper replaces the default first-seen grouper
+func (p *pipeline) SetGrouper(grouper Grouper) {
+	p.grouper = grouper
+}
+
+// reset prepares the components and clears the state for a new search of
+// roots
+func (p *pipeline) reset(roots []string) error {
+	p.roots = roots
+	p.activeWalker = p.walker
+	if p.activeWalker == nil {
+		rules, err := ParseExcludeRules(p.excludedDirs, p.excludedFiles)
+		if err != nil {
+			return err
+		}
+		p.activeWalker = NewWalker(WalkOptions{
+			Rules:         rules,
+			RespectIgnore: p.respectIgnore,
+			Symlinks:      p.symlinkPolicy,
+			OneFileSystem: p.oneFileSystem,
+		})
+	}
+
+	p.activeStages = p.stages
+	if p.activeStages == nil {
+		p.activeStages = []Stage{SizeStage()}
+		if p.sampleSize > 0 {
+			p.activeStages = append(p.activeStages, NewPartialHashStage(p.algorithm, p.sampleSize))
+		}
+	}
+
+	p.activeHasher = p.hasher
+	if p.activeHasher == nil {
+		p.activeHasher = NewContentHasher(p.algorithm, p.cache)
+	}
+
+	p.activeGrouper = p.grouper
+	if p.activeGrouper == nil {
+		p.activeGrouper = NewGrouper(p.verify)
+	}
+	p.activeGrouper.Reset()
+
+	p.buckets = make([]map[string]*candidateBucket, len(p.activeStages))
+	for i := range p.buckets {
+		p.buckets[i] = make(map[string]*candidateBucket)
+	}
+	p.filesScanned = 0
+	p.filesFiltered = 0
+	p.emptyFiles = nil
+	p.emptyCount = 0
+	p.linkTargets = make(map[fileID]string)
+	p.hardLinks = make(map[string][]string)
+	p.linkTotals = make(map[string]uint64)
+	p.linkCount = 0
+	p.symlinks = nil
+	p.fileErrors = nil
+	p.unreadable = make(map[int64][]string)
+	p.filesHashed = 0
+	p.eliminated = make(map[string]int)
+	p.bucketKeys = nil
+	p.nextBucket = 0
+	p.pending = make(map[string]int)
+	p.bucketHashes = make(map[string][]string)
+	p.hashSeen = make(map[string]bool)
+	p.handedOut = DuplicateStats{}
+	return nil
+}
+
+// admit counts a walked file, applies the filters to it and sets it aside if
+// it is a reported symlink, if it is empty and the empty-file policy says so,
+// or if it is another hard link to a file already admitted. Only the first link found to each file
+// moves on, so every file on disk is hashed at most once. Files that pass the
+// filters are passed to the admit hook of ctx, if any.
+func (p *pipeline) admit(ctx context.Context, file FileEntry, progressChan chan<- int) bool {
+	p.mu.Lock()
+	p.filesScanned++
+	p.mu.Unlock()
+
+	if file.SymlinkTarget != "" {
+		p.mu.Lock()
+		p.symlinks = append(p.symlinks, Symlink{Path: file.Path, Target: file.SymlinkTarget})
+		p.mu.Unlock()
+		reportProgress(progressChan)
+		return false
+	}
+
+	for _, filter := range p.filters {
+		if !filter.Include(file) {
+			p.mu.Lock()
+			p.filesFiltered++
+			p.mu.Unlock()
+			reportProgress(progressChan)
+			return false
+		}
+	}
+	if hook, ok := ctx.Value(admitHookKey{}).(func(FileEntry)); ok {
+		hook(file)
+	}
+
+	if file.Size == 0 && p.emptyPolicy != EmptyDuplicate {
+		p.mu.Lock()
+		p.emptyCount++
+		if p.emptyPolicy == EmptyReport {
+			p.emptyFiles = append(p.emptyFiles, file.Path)
+		}
+		p.mu.Unlock()
+		reportProgress(progressChan)
+		return false
+	}
+
+	// Followed symlinks make any file reachable by several paths, not just
+	// those with several hard links
+	if (file.Links > 1 || p.symlinkPolicy == SymlinksFollow) && file.Inode != 0 {
+		id := fileID{dev: file.Device, ino: file.Inode}
+		p.mu.Lock()
+		first, seen := p.linkTargets[id]
+		if seen {
+			p.hardLinks[first] = append(p.hardLinks[first], file.Path)
+			p.linkCount++
+		} else {
+			p.linkTargets[id] = file.Path
+			p.linkTotals[file.Path] = file.Links
+		}
+		p.mu.Unlock()
+		if seen {
+			reportProgress(progressChan)
+			return false
+		}
+	}
+	return true
+}
+
+// runStage computes stage i's key for a candidate and returns the
+// candidates that move on to stage i+1
+func (p *pipeline) runStage(ctx context.Context, i int, c candidate, progressChan chan<- int) []candidate {
+	key, err := p.activeStages[i].Key(ctx, c.file)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		// Log warning but continue processing
+		p.recordError(c.file, err)
+		reportProgress(progressChan)
+		return nil
+	}
+	c.key += "\x00" + key
+	if i == 0 {
+		c.bucket = c.key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bucket, exists := p.buckets[i][c.key]
+	if !exists {
+		bucket = &candidateBucket{}
+		p.buckets[i][c.key] = bucket
+	}
+	return bucket.add(c)
+}
+
+// advance runs a candidate through the stages from i onwards and fully
+// hashes it if it survives them all. Nothing more is done once ctx is
+// cancelled.
+func (p *pipeline) advance(ctx context.Context, i int, c candidate, progressChan chan<- int) {
+	if ctx.Err() != nil {
+		return
+	}
+	if i == len(p.activeStages) {
+		p.hashCandidate(ctx, c, progressChan)
+		return
+	}
+	for _, next := range p.runStage(ctx, i, c, progressChan) {
+		p.advance(ctx, i+1, next, progressChan)
+	}
+}
+
+// hashCandidate fully hashes a candidate's file and hands it to the grouper
+func (p *pipeline) hashCandidate(ctx context.Context, c candidate, progressChan chan<- int) {
+	file := c.file
+	hash, err := p.activeHasher.Hash(ctx, file)
+	if ctx.Err() != nil {
+		// Abandoned part-way through; the file is neither hashed nor reported
+		return
+	}
+
+	p.mu.Lock()
+	p.filesHashed++
+	p.mu.Unlock()
+
+	if err == nil {
+		err = p.activeGrouper.Add(file, hash)
+	}
+	if err == nil {
+		p.recordHash(c.bucket, hash)
+	}
+	if err != nil {
+		// Log warning but continue processing
+		p.recordError(file, err)
+	}
+	reportProgress(progressChan)
+}
+
+// recordError logs a file that could not be read as a warning and keeps it
+// for Errors
+func (p *pipeline) recordError(file FileEntry, err error) {
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+
+	p.mu.Lock()
+	p.fileErrors = append(p.fileErrors, FileError{Path: file.Path, Error: err.Error()})
+	p.unreadable[file.Size] = append(p.unreadable[file.Size], file.Path)
+	p.mu.Unlock()
+}
+
+// finish counts the files each stage eliminated. Files still alone in a
+// bucket once the walk is over never met a matching file at that stage.
+func (p *pipeline) finish(progressChan chan<- int) {
+	for i, stage := range p.activeStages {
+		for _, bucket := range p.buckets[i] {
+			if bucket.count == 1 {
+				p.eliminated[stage.Name()]++
+				reportProgress(progressChan)
+			}
+		}
+	}
+}
+
+// Collisions returns the hash collisions found by the last verified search,
+// sorted by path
+func (p *pipeline) Collisions() []HashCollision {
+	if p.activeGrouper == nil {
+		return nil
+	}
+	collisions := append([]HashCollision(nil), p.activeGrouper.Collisions()...)
+	sort.Slice(collisions, func(i, j int) bool {
+		if collisions[i].Original != collisions[j].Original {
+			return collisions[i].Original < collisions[j].Original
+		}
+		return collisions[i].Candidate < collisions[j].Candidate
+	})
+	return collisions
+}
+
+// EmptyFiles returns the zero-length files set aside by the last search under
+// the EmptyReport policy, in the order they were found
+func (p *pipeline) EmptyFiles() []string {
+	return p.emptyFiles
+}
+
+// HardLinks returns the hard links found by the last search, keyed by the
+// first path found to each linked file. Links share their storage, so
+// removing one reclaims no space.
+func (p *pipeline) HardLinks() map[string][]string {
+	return p.hardLinks
+}
+
+// Symlinks returns the symbolic links found by the last search under the
+// SymlinksReport policy, in the order they were found
+func (p *pipeline) Symlinks() []Symlink {
+	return p.symlinks
+}
+
+// Errors returns the files the last search could not read, sorted by path
+func (p *pipeline) Errors() []FileError {
+	fileErrors := append([]FileError(nil), p.fileErrors...)
+	sort.SliceStable(fileErrors, func(i, j int) bool {
+		return fileErrors[i].Path < fileErrors[j].Path
+	})
+	return fileErrors
+}
+
+// duplicates returns the duplicates found by the last search, arranged by
+// the original-selection rule
+func (p *pipeline) duplicates() []Duplicate {
+	if p.activeGrouper == nil {
+		return []Duplicate{}
+	}
+	return p.annotate(ArrangeDuplicates(p.activeGrouper.Duplicates(), p.originalRule, p.roots))
+}
+
+// annotate fills in what the duplicates' groups owe to the rest of the
+// search. Duplicates with hard links the search did not find get no
+// allocated space: deleting such a duplicate, and the links to it that were
+// found, frees nothing while a link elsewhere keeps the file. Every duplicate
+// also lists the unreadable files of its size, any of which may belong to its
+// group.
+func (p *pipeline) annotate(duplicates []Duplicate) []Duplicate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Copied, so the lists handed out never change afterwards
+	unreadable := make(map[int64][]string)
+	for i, dup := range duplicates {
+		if total := p.linkTotals[dup.Duplicate]; total > uint64(1+len(p.hardLinks[dup.Duplicate])) {
+			duplicates[i].Allocated = 0
+		}
+		if len(p.unreadable[dup.Size]) == 0 {
+			continue
+		}
+		paths, ok := unreadable[dup.Size]
+		if !ok {
+			paths = append([]string(nil), p.unreadable[dup.Size]...)
+			sort.Strings(paths)
+			unreadable[dup.Size] = paths
+		}
+		duplicates[i].Unreadable = paths
+	}
+	return duplicates
+}
+
+// Stats returns statistics for the last search, including how many files
+// each pipeline stage eliminated
+func (p *pipeline) Stats() DuplicateStats {
+	stats := GetDuplicateStats(p.duplicates())
+	// Groups handed to a GroupHandler are counted but no longer held
+	stats.TotalDuplicates += p.handedOut.TotalDuplicates
+	stats.UniqueOriginals += p.handedOut.UniqueOriginals
+	stats.TotalDuplicateFiles += p.handedOut.TotalDuplicateFiles
+	stats.WastedBytes += p.handedOut.WastedBytes
+	stats.ReclaimableBytes += p.handedOut.ReclaimableBytes
+	stats.FilesScanned = p.filesScanned
+	stats.FilesFiltered = p.filesFiltered
+	stats.EmptyFiles = p.emptyCount
+	stats.HardLinks = p.linkCount
+	stats.Symlinks = len(p.symlinks)
+	stats.SkippedUniqueSize = p.eliminated[StageSize]
+	stats.SkippedPartialHash = p.eliminated[StagePartial]
+	stats.StageEliminations = p.eliminated
+	stats.FilesHashed = p.filesHashed
+	stats.HashCollisions = len(p.Collisions())
+	if counter, ok := p.activeHasher.(interface{ CacheHits() int }); ok {
+		stats.CacheHits = counter.CacheHits()
+	}
+	return stats
+}
+
+// verifyRoots checks that every root directory exists and that no root lies
+// inside another, which would scan its files twice
+func verifyRoots(roots []string) error {
+	if len(roots) == 0 {
+		return fmt.Errorf("no directory to search")
+	}
+
+	absRoots := make([]string, len(roots))
+	for i, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			return fmt.Errorf("directory does not exist: %s", root)
+		}
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve directory %s: %w", root, err)
+		}
+		absRoots[i] = abs
+	}
+
+	for i := range absRoots {
+		for j := range absRoots {
+			if i != j && pathWithin(absRoots[j], absRoots[i]) {
+				return fmt.Errorf("directories overlap: %s is inside %s", roots[j], roots[i])
+			}
+		}
+	}
+	return nil
+}
+
+// pathWithin reports whether path is dir or lies beneath it
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,28 @@
+package core_test
+
+import (
+	"testing"
+
+	"clone-spotter/internal/core"
+	"clone-spotter/internal/core/scannertest"
+)
+
+func TestDuplicateFinder(t *testing.T) {
+	err := scannertest.TestScanner(func(algorithm core.HashAlgorithm, excludedDirs []string) core.Scanner {
+		return core.NewDuplicateFinder(algorithm, excludedDirs)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentDuplicateFinder(t *testing.T) {
+	for _, workers := range []int{2, 8} {
+		err := scannertest.TestScanner(func(algorithm core.HashAlgorithm, excludedDirs []string) core.Scanner {
+			return core.NewConcurrentDuplicateFinder(algorithm, excludedDirs, workers)
+		})
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+	}
+}
@@ -0,0 +1,740 @@
+// Package scannertest implements a conformance suite for core.Scanner
+// implementations, in the spirit of testing/fstest. Every Scanner must pass
+// TestScanner; call it from the implementation's tests:
+//
+//	if err := scannertest.TestScanner(factory); err != nil {
+//		t.Fatal(err)
+//	}
+package scannertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"clone-spotter/internal/core"
+)
+
+// Factory creates the Scanner under test
+type Factory func(algorithm core.HashAlgorithm, excludedDirs []string) core.Scanner
+
+// excludedDir is the fixture directory every scanner is told to skip
+const excludedDir = "skipme"
+
+// fixture describes a tree of files and the duplicate groups expected in it
+type fixture struct {
+	files  map[string]string
+	links  map[string]string // hard link path to target path
+	groups [][]string
+	empty  []string
+}
+
+// standardFixture covers the cases every scanner must get right: plain
+// duplicates across directories, groups of more than two, files that share a
+// size but not content, files that share a head and tail but differ in the
+// middle, duplicates hidden inside an excluded directory, empty files, which
+// the default policy reports separately, and hard links, which are never
+// duplicates of each other
+func standardFixture() fixture {
+	sample := strings.Repeat("a", int(core.DefaultSampleSize))
+	return fixture{
+		files: map[string]string{
+			"a/one.txt":                       "duplicate content",
+			"b/one-copy.txt":                  "duplicate content",
+			"a/deep/nested/two.txt":           "three of a kind",
+			"b/two-copy.txt":                  "three of a kind",
+			"c/two-copy.txt":                  "three of a kind",
+			"a/same-size-1.txt":               "same size AAA",
+			"b/same-size-2.txt":               "same size BBB",
+			"c/unique.txt":                    "a file with a size nobody else has",
+			"a/sampled-1.bin":                 sample + "middle one" + sample,
+			"b/sampled-2.bin":                 sample + "middle two" + sample,
+			"c/sampled-3.bin":                 sample + "middle one" + sample,
+			excludedDir + "/x.txt":            "duplicate content",
+			"a/not-" + excludedDir + "/z.txt": "duplicate content",
+			"a/" + excludedDir + "/y.txt":     "three of a kind",
+			"a/empty-1.txt":                   "",
+			"c/empty-2.txt":                   "",
+			excludedDir + "/empty-3.txt":      "",
+		},
+		groups: [][]string{
+			{"a/one.txt", "b/one-copy.txt", "a/not-" + excludedDir + "/z.txt"},
+			{"a/deep/nested/two.txt", "b/two-copy.txt", "c/two-copy.txt"},
+			{"a/sampled-1.bin", "c/sampled-3.bin"},
+		},
+		links: map[string]string{
+			"c/one-link.txt":   "a/one.txt",
+			"c/same-size-link": "a/same-size-1.txt",
+		},
+		empty: []string{"a/empty-1.txt", "c/empty-2.txt"},
+	}
+}
+
+// visibleFiles returns how many fixture files and links lie outside excluded
+// directories
+func (f fixture) visibleFiles() int {
+	count := 0
+	for path := range f.files {
+		if !excluded(path) {
+			count++
+		}
+	}
+	for path := range f.links {
+		if !excluded(path) {
+			count++
+		}
+	}
+	return count
+}
+
+// excluded reports whether a fixture path lies inside an excluded directory
+func excluded(path string) bool {
+	return strings.HasPrefix(path, excludedDir+"/") || strings.Contains(path, "/"+excludedDir+"/")
+}
+
+// hardLinks returns the expected hard link groups, keyed by target
+func (f fixture) hardLinks() map[string][]string {
+	links := make(map[string][]string)
+	for link, target := range f.links {
+		links[target] = append(links[target], link)
+	}
+	return links
+}
+
+// withoutLinkDetection returns the groups expected on platforms that cannot
+// tell hard links apart from copies, where every link is a duplicate of its
+// target
+func (f fixture) withoutLinkDetection() fixture {
+	groups := make([][]string, 0, len(f.groups))
+	for _, group := range f.groups {
+		groups = append(groups, append([]string(nil), group...))
+	}
+	for target, links := range f.hardLinks() {
+		found := false
+		for i, group := range groups {
+			for _, path := range group {
+				if path == target {
+					groups[i] = append(groups[i], links...)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			groups = append(groups, append([]string{target}, links...))
+		}
+	}
+	f.groups = groups
+	f.links = nil
+	return f
+}
+
+// build writes the fixture into a new temporary directory
+func (f fixture) build() (string, error) {
+	root, err := os.MkdirTemp("", "scannertest-")
+	if err != nil {
+		return "", err
+	}
+	for path, content := range f.files {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+	}
+	for link, target := range f.links {
+		linkPath := filepath.Join(root, filepath.FromSlash(link))
+		if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+		if err := os.Link(filepath.Join(root, filepath.FromSlash(target)), linkPath); err != nil {
+			os.RemoveAll(root)
+			return "", err
+		}
+	}
+	return root, nil
+}
+
+// linksDetected reports whether hard links in a built fixture can be told
+// apart from copies on this platform
+func (f fixture) linksDetected(root string) bool {
+	for link := range f.links {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(link)))
+		if err != nil {
+			return false
+		}
+		_, _, ok := core.FileIdentity(info)
+		return ok && core.LinkCount(info) > 1
+	}
+	return true
+}
+
+// TestScanner builds fixture trees in temporary directories and checks that
+// scanners created by newScanner report exactly the expected duplicates,
+// with every supported algorithm. It returns an error describing every check
+// that failed, or nil if the scanner conforms.
+func TestScanner(newScanner Factory) error {
+	var errs []error
+	for _, algorithm := range core.GetSupportedAlgorithms() {
+		if err := testStandardFixture(newScanner, algorithm); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", algorithm, err))
+		}
+	}
+	if err := testEmptyDirectory(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("empty directory: %w", err))
+	}
+	if err := testMissingRoot(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("missing root: %w", err))
+	}
+	if err := testCancelled(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("cancelled context: %w", err))
+	}
+	if err := testSymlinks(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("symlinks: %w", err))
+	}
+	if err := testMultipleRoots(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("multiple roots: %w", err))
+	}
+	if err := testGroupHandler(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("group handler: %w", err))
+	}
+	if err := testReclaimable(newScanner); err != nil {
+		errs = append(errs, fmt.Errorf("reclaimable space: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func testStandardFixture(newScanner Factory, algorithm core.HashAlgorithm) error {
+	f := standardFixture()
+	root, err := f.build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	visible := f.visibleFiles()
+	if !f.linksDetected(root) {
+		f = f.withoutLinkDetection()
+	}
+
+	scanner := newScanner(algorithm, []string{excludedDir})
+
+	// Run twice to check that no state leaks from one search to the next
+	for run := 1; run <= 2; run++ {
+		duplicates, err := scanner.SearchDuplicates(root, nil)
+		if err != nil {
+			return fmt.Errorf("run %d: search failed: %w", run, err)
+		}
+		if err := checkGroups(root, duplicates, f.groups); err != nil {
+			return fmt.Errorf("run %d: %w", run, err)
+		}
+		if err := checkStats(scanner.Stats(), duplicates, visible); err != nil {
+			return fmt.Errorf("run %d: %w", run, err)
+		}
+		if err := checkEmpty(root, scanner.EmptyFiles(), f.empty); err != nil {
+			return fmt.Errorf("run %d: %w", run, err)
+		}
+		if err := checkHardLinks(root, scanner.HardLinks(), f.hardLinks()); err != nil {
+			return fmt.Errorf("run %d: %w", run, err)
+		}
+		if fileErrors := scanner.Errors(); len(fileErrors) != 0 {
+			return fmt.Errorf("run %d: expected no file errors, got %v", run, fileErrors)
+		}
+		for _, dup := range duplicates {
+			if dup.Hash == "" {
+				return fmt.Errorf("run %d: duplicate %s has no hash", run, dup.Duplicate)
+			}
+		}
+		if err := checkOrder(duplicates); err != nil {
+			return fmt.Errorf("run %d: %w", run, err)
+		}
+	}
+	return nil
+}
+
+func testEmptyDirectory(newScanner Factory) error {
+	root, err := os.MkdirTemp("", "scannertest-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	duplicates, err := newScanner(core.MD5, nil).SearchDuplicates(root, nil)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	if len(duplicates) != 0 {
+		return fmt.Errorf("expected no duplicates, got %v", duplicates)
+	}
+	return nil
+}
+
+func testMissingRoot(newScanner Factory) error {
+	root := filepath.Join(os.TempDir(), "scannertest-does-not-exist")
+	if _, err := newScanner(core.MD5, nil).SearchDuplicates(root, nil); err == nil {
+		return fmt.Errorf("expected an error for %s", root)
+	}
+	return nil
+}
+
+// groupHandlerScanner is implemented by scanners that hand out groups while
+// searching; the group handler checks only run against those
+type groupHandlerScanner interface {
+	core.Scanner
+	SetGroupHandler(handler core.GroupHandler)
+}
+
+// testGroupHandler checks that the groups handed out during a search are
+// exactly those a search without a handler returns, each handed out once and
+// whole, in the same order on every run, that they are released once handed
+// out, and that an interrupted search hands out every group it counts
+func testGroupHandler(newScanner Factory) error {
+	if _, ok := newScanner(core.MD5, nil).(groupHandlerScanner); !ok {
+		return nil
+	}
+
+	f := standardFixture()
+	root, err := f.build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	plain := newScanner(core.MD5, []string{excludedDir})
+	want, err := plain.SearchDuplicates(root, nil)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	wantStats := plain.Stats()
+
+	var firstRun []core.Duplicate
+	for run := 1; run <= 2; run++ {
+		scanner := newScanner(core.MD5, []string{excludedDir}).(groupHandlerScanner)
+		handled, duplicates, repeated, err := searchHandingOut(scanner, root)
+		if err != nil {
+			return fmt.Errorf("run %d: search failed: %w", run, err)
+		}
+		if len(repeated) > 0 {
+			return fmt.Errorf("run %d: groups handed out more than once: %v", run, repeated)
+		}
+		if len(duplicates) != 0 {
+			return fmt.Errorf("run %d: groups handed out were returned again: %v", run, duplicates)
+		}
+		got := core.ArrangeDuplicates(handled, core.DefaultOriginalRule, nil)
+		if len(got) != len(want) {
+			return fmt.Errorf("run %d: handled %d duplicates, a search without a handler returned %d", run, len(got), len(want))
+		}
+		for i := range got {
+			if got[i].Original != want[i].Original || got[i].Duplicate != want[i].Duplicate {
+				return fmt.Errorf("run %d: handled %s as a duplicate of %s, a search without a handler returned %s of %s",
+					run, got[i].Duplicate, got[i].Original, want[i].Duplicate, want[i].Original)
+			}
+		}
+		stats := scanner.Stats()
+		if stats.TotalDuplicates != wantStats.TotalDuplicates || stats.UniqueOriginals != wantStats.UniqueOriginals ||
+			stats.TotalDuplicateFiles != wantStats.TotalDuplicateFiles || stats.WastedBytes != wantStats.WastedBytes ||
+			stats.ReclaimableBytes != wantStats.ReclaimableBytes {
+			return fmt.Errorf("run %d: stats %+v differ from those of a search without a handler %+v", run, stats, wantStats)
+		}
+
+		if run == 1 {
+			firstRun = handled
+			continue
+		}
+		for i := range handled {
+			if handled[i].Original != firstRun[i].Original || handled[i].Duplicate != firstRun[i].Duplicate {
+				return fmt.Errorf("groups handed out in a different order on the second run: %s of %s, then %s of %s",
+					firstRun[i].Duplicate, firstRun[i].Original, handled[i].Duplicate, handled[i].Original)
+			}
+		}
+	}
+
+	// Interrupt the search as soon as the first group is handed out. Groups
+	// are only handed out once the walk is over, so a scanner that has
+	// hashed every file by then finishes the search regardless.
+	scanner := newScanner(core.MD5, []string{excludedDir}).(groupHandlerScanner)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var handled int
+	var mu sync.Mutex
+	scanner.SetGroupHandler(func(group []core.Duplicate) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled += len(group)
+		cancel()
+	})
+	duplicates, err := scanner.SearchDuplicatesContext(ctx, root, nil)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted: expected context.Canceled, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(duplicates) != 0 {
+		return fmt.Errorf("interrupted: groups kept instead of handed out: %v", duplicates)
+	}
+	if total := scanner.Stats().TotalDuplicates; total != handled {
+		return fmt.Errorf("interrupted: stats count %d duplicates, %d were handed out", total, handled)
+	}
+	return nil
+}
+
+// searchHandingOut searches root with a group handler set and returns the
+// duplicates handed out, those returned and the originals of any group handed
+// out more than once
+func searchHandingOut(scanner groupHandlerScanner, root string) (handled, returned []core.Duplicate, repeated []string, err error) {
+	originals := make(map[string]bool)
+	var mu sync.Mutex
+	scanner.SetGroupHandler(func(group []core.Duplicate) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(group) > 0 {
+			if originals[group[0].Original] {
+				repeated = append(repeated, group[0].Original)
+			}
+			originals[group[0].Original] = true
+		}
+		handled = append(handled, group...)
+	})
+
+	returned, err = scanner.SearchDuplicates(root, nil)
+	mu.Lock()
+	defer mu.Unlock()
+	return handled, returned, repeated, err
+}
+
+// symlinkScanner is implemented by scanners whose symlink handling can be
+// configured; the symlink checks only run against those
+type symlinkScanner interface {
+	core.Scanner
+	SetSymlinkPolicy(policy core.SymlinkPolicy)
+}
+
+// testSymlinks checks every symlink policy against a tree holding a link to
+// a file and a link back to the root, which must not be walked forever
+func testSymlinks(newScanner Factory) error {
+	if _, ok := newScanner(core.MD5, nil).(symlinkScanner); !ok {
+		return nil
+	}
+
+	f := fixture{
+		files: map[string]string{
+			"a/one.txt":      "duplicate content",
+			"b/one-copy.txt": "duplicate content",
+		},
+		groups: [][]string{{"a/one.txt", "b/one-copy.txt"}},
+	}
+	root, err := f.build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	links := map[string]string{
+		"c/link.txt": filepath.Join("..", "a", "one.txt"),
+		"c/loop":     "..",
+	}
+	if err := os.MkdirAll(filepath.Join(root, "c"), 0755); err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			// Symlinks are unavailable here, e.g. without privileges on Windows
+			return nil
+		}
+	}
+
+	var errs []error
+	for _, policy := range core.GetSymlinkPolicies() {
+		scanner := newScanner(core.MD5, nil).(symlinkScanner)
+		scanner.SetSymlinkPolicy(policy)
+
+		duplicates, err := scanner.SearchDuplicates(root, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: search failed: %w", policy, err))
+			continue
+		}
+		if err := checkGroups(root, duplicates, f.groups); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", policy, err))
+		}
+
+		var reported []string
+		for _, link := range scanner.Symlinks() {
+			reported = append(reported, relative(root, link.Path))
+		}
+		sort.Strings(reported)
+
+		wantReported, wantAliases := []string(nil), map[string][]string{}
+		switch policy {
+		case core.SymlinksReport:
+			wantReported = []string{"c/link.txt", "c/loop"}
+		case core.SymlinksFollow:
+			wantAliases = map[string][]string{"a/one.txt": {"c/link.txt"}}
+		}
+		if fmt.Sprint(reported) != fmt.Sprint(wantReported) {
+			errs = append(errs, fmt.Errorf("%s: expected symlinks %v, got %v", policy, wantReported, reported))
+		}
+		if err := checkHardLinks(root, scanner.HardLinks(), wantAliases); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", policy, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// testMultipleRoots searches the standard fixture's top-level directories as
+// separate roots, which must find the same groups as searching their parent
+// and tag every file with its own root
+func testMultipleRoots(newScanner Factory) error {
+	f := standardFixture()
+	root, err := f.build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	if !f.linksDetected(root) {
+		f = f.withoutLinkDetection()
+	}
+
+	roots := []string{filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "c")}
+	scanner := newScanner(core.MD5, []string{excludedDir})
+	duplicates, err := scanner.SearchRoots(context.Background(), roots, nil)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	var errs []error
+	if err := checkGroups(root, duplicates, f.groups); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkHardLinks(root, scanner.HardLinks(), f.hardLinks()); err != nil {
+		errs = append(errs, err)
+	}
+	for _, dup := range duplicates {
+		for path, tag := range map[string]string{dup.Original: dup.OriginalRoot, dup.Duplicate: dup.DuplicateRoot} {
+			if want := filepath.Join(root, strings.SplitN(relative(root, path), "/", 2)[0]); tag != want {
+				errs = append(errs, fmt.Errorf("%s tagged with root %s, expected %s", path, tag, want))
+			}
+		}
+	}
+
+	// Overlapping roots would scan the same files twice
+	overlapping := []string{root, filepath.Join(root, "a")}
+	if _, err := newScanner(core.MD5, nil).SearchRoots(context.Background(), overlapping, nil); err == nil {
+		errs = append(errs, fmt.Errorf("expected an error for overlapping roots %v", overlapping))
+	}
+	return errors.Join(errs...)
+}
+
+// testReclaimable checks that a duplicate with hard links only counts as
+// reclaimable when the search found every link to it
+func testReclaimable(newScanner Factory) error {
+	f := fixture{
+		files: map[string]string{
+			"in/a/original":  "every link found",
+			"in/b/duplicate": "every link found",
+			"in/c/original":  "a link outside the search",
+			"in/d/duplicate": "a link outside the search",
+		},
+		links: map[string]string{
+			"in/b/link":  "in/b/duplicate",
+			"out/d/link": "in/d/duplicate",
+		},
+	}
+	root, err := f.build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+	if !f.linksDetected(root) {
+		return nil
+	}
+
+	scanner := newScanner(core.MD5, nil)
+	duplicates, err := scanner.SearchDuplicates(filepath.Join(root, "in"), nil)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	if len(duplicates) != 2 {
+		return fmt.Errorf("expected 2 duplicates, got %v", duplicates)
+	}
+
+	var errs []error
+	var reclaimable int64
+	for _, dup := range duplicates {
+		info, err := os.Stat(dup.Duplicate)
+		if err != nil {
+			return err
+		}
+		want := core.AllocatedSize(info)
+		if strings.HasPrefix(relative(root, dup.Duplicate), "in/d/") {
+			want = 0
+		}
+		if dup.Allocated != want {
+			errs = append(errs, fmt.Errorf("%s: %d bytes reclaimable, expected %d", relative(root, dup.Duplicate), dup.Allocated, want))
+		}
+		reclaimable += want
+	}
+	if stats := scanner.Stats(); stats.ReclaimableBytes != reclaimable {
+		errs = append(errs, fmt.Errorf("stats report %d bytes reclaimable, expected %d", stats.ReclaimableBytes, reclaimable))
+	}
+	return errors.Join(errs...)
+}
+
+func testCancelled(newScanner Factory) error {
+	root, err := standardFixture().build()
+	if err != nil {
+		return fmt.Errorf("failed to build fixture: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	duplicates, err := newScanner(core.MD5, nil).SearchDuplicatesContext(ctx, root, nil)
+	if !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(duplicates) != 0 {
+		return fmt.Errorf("expected no duplicates from a search cancelled before it began, got %v", duplicates)
+	}
+	return nil
+}
+
+// checkGroups compares the reported duplicates with the expected groups.
+// Which member of a group is the original is not checked, since concurrent
+// scanners may legitimately pick any of them.
+func checkGroups(root string, duplicates []core.Duplicate, expected [][]string) error {
+	got := make(map[string][]string)
+	for original, copies := range core.GatherDuplicates(duplicates) {
+		group := []string{relative(root, original)}
+		for _, dup := range copies {
+			group = append(group, relative(root, dup))
+		}
+		sort.Strings(group)
+		got[group[0]] = group
+	}
+
+	want := make(map[string][]string)
+	for _, group := range expected {
+		group = append([]string(nil), group...)
+		sort.Strings(group)
+		want[group[0]] = group
+	}
+
+	var errs []error
+	for key, group := range want {
+		if fmt.Sprint(got[key]) != fmt.Sprint(group) {
+			errs = append(errs, fmt.Errorf("expected group %v, got %v", group, got[key]))
+		}
+	}
+	for key, group := range got {
+		if _, ok := want[key]; !ok {
+			errs = append(errs, fmt.Errorf("unexpected group %v", group))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkOrder checks that duplicates follow the default original-selection
+// rule and the canonical order: the lexicographically first path of each
+// group is its original, groups are sorted by original and the duplicates of
+// each group by path
+func checkOrder(duplicates []core.Duplicate) error {
+	for i, dup := range duplicates {
+		if dup.Duplicate <= dup.Original {
+			return fmt.Errorf("%s is reported as a duplicate of %s, which sorts after it", dup.Duplicate, dup.Original)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := duplicates[i-1]
+		if dup.Original < prev.Original || (dup.Original == prev.Original && dup.Duplicate <= prev.Duplicate) {
+			return fmt.Errorf("duplicate %s of %s is out of order after %s of %s", dup.Duplicate, dup.Original, prev.Duplicate, prev.Original)
+		}
+	}
+	return nil
+}
+
+// checkStats checks that the statistics agree with the reported duplicates
+// and that every admitted file was accounted for by exactly one stage
+func checkStats(stats core.DuplicateStats, duplicates []core.Duplicate, visibleFiles int) error {
+	var errs []error
+	if stats.TotalDuplicates != len(duplicates) {
+		errs = append(errs, fmt.Errorf("stats report %d duplicates, search returned %d", stats.TotalDuplicates, len(duplicates)))
+	}
+	if stats.FilesScanned != visibleFiles {
+		errs = append(errs, fmt.Errorf("stats report %d files scanned, expected %d", stats.FilesScanned, visibleFiles))
+	}
+
+	eliminated := 0
+	for _, count := range stats.StageEliminations {
+		eliminated += count
+	}
+	if accounted := stats.FilesFiltered + stats.EmptyFiles + stats.HardLinks + eliminated + stats.FilesHashed; accounted != stats.FilesScanned {
+		errs = append(errs, fmt.Errorf("stats account for %d files, %d were scanned", accounted, stats.FilesScanned))
+	}
+	return errors.Join(errs...)
+}
+
+// checkEmpty compares the reported empty files with the expected ones
+func checkEmpty(root string, empty, expected []string) error {
+	got := make([]string, 0, len(empty))
+	for _, path := range empty {
+		got = append(got, relative(root, path))
+	}
+	sort.Strings(got)
+
+	want := append([]string(nil), expected...)
+	sort.Strings(want)
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("expected empty files %v, got %v", want, got)
+	}
+	return nil
+}
+
+// checkHardLinks compares the reported hard link groups with the expected
+// ones. Since the walk visits a directory's entries in lexical order, the
+// target is always found before its links.
+func checkHardLinks(root string, links, expected map[string][]string) error {
+	got := make(map[string][]string)
+	for target, paths := range links {
+		group := make([]string, 0, len(paths))
+		for _, path := range paths {
+			group = append(group, relative(root, path))
+		}
+		sort.Strings(group)
+		got[relative(root, target)] = group
+	}
+
+	want := make(map[string][]string)
+	for target, paths := range expected {
+		group := append([]string(nil), paths...)
+		sort.Strings(group)
+		want[target] = group
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("expected hard links %v, got %v", want, got)
+	}
+	return nil
+}
+
+// relative returns path relative to root, with forward slashes
+func relative(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
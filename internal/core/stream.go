@@ -0,0 +1,202 @@
+package core
+
+import (
+	"sort"
+)
+
+// GroupHandler receives a duplicate group as soon as no further file can join
+// it: its pairs share one original, chosen by the original-selection rule,
+// with the duplicates sorted by path
+type GroupHandler func(group []Duplicate)
+
+// GroupLookup is implemented by Groupers that can hand over the duplicates
+// recorded for a single hash. Without it, groups are only handed to the
+// GroupHandler once the whole search is over.
+type GroupLookup interface {
+	// TakeDuplicates returns the duplicates recorded for hash and forgets
+	// them, so they are no longer part of Duplicates
+	TakeDuplicates(hash string) []Duplicate
+}
+
+// SetGroupHandler sets a function called with every duplicate group once it
+// is complete, while the search is still running. Files are hashed as the
+// walk finds them, as without a handler, but any later file could still join
+// a group, so no group is handed out before the walk is over. From then on
+// the groups of each first-stage bucket are handed out, sorted by original,
+// as soon as it and every bucket before it in key order are finished, while
+// the concurrent engine's workers hash the rest. Groups come out in the same
+// order on every run whatever the number of workers. Calls are serialized.
+//
+// Groups handed out are released: the search no longer returns them, and
+// Stats counts them without listing them in DuplicateGroups. Until the walk
+// is over a search holds as much as one without a handler. When a search is
+// interrupted, the groups found so far are handed out before it returns.
+func (p *pipeline) SetGroupHandler(handler GroupHandler) {
+	p.groupHandler = handler
+}
+
+// queued records that a candidate of a first-stage bucket was queued for a
+// worker, so the bucket's groups wait until it is settled
+func (p *pipeline) queued(bucket string) {
+	if p.groupHandler == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending[bucket]++
+}
+
+// walked fixes the order the buckets are handed out in once the walk is over,
+// and hands out the groups of those already finished
+func (p *pipeline) walked() {
+	if p.groupHandler == nil {
+		return
+	}
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.pending)+len(p.bucketHashes))
+	for key := range p.pending {
+		keys = append(keys, key)
+	}
+	for key := range p.bucketHashes {
+		if _, ok := p.pending[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	p.bucketKeys = keys
+	p.mu.Unlock()
+	p.emitReady()
+}
+
+// settled records that a queued candidate is done with, handing out the
+// groups of every bucket that is now finished and follows only finished
+// buckets
+func (p *pipeline) settled(bucket string) {
+	p.mu.Lock()
+	p.pending[bucket]--
+	p.mu.Unlock()
+	p.emitReady()
+}
+
+// emitReady hands out the groups of finished buckets, in key order, up to the
+// first bucket still pending. Nothing is handed out before the walk is over.
+func (p *pipeline) emitReady() {
+	p.emitMu.Lock()
+	defer p.emitMu.Unlock()
+	for {
+		p.mu.Lock()
+		if p.nextBucket == len(p.bucketKeys) || p.pending[p.bucketKeys[p.nextBucket]] > 0 {
+			p.mu.Unlock()
+			return
+		}
+		bucket := p.bucketKeys[p.nextBucket]
+		p.nextBucket++
+		delete(p.pending, bucket)
+		p.mu.Unlock()
+		p.emitBucket(bucket)
+	}
+}
+
+// recordHash remembers that a file of a first-stage bucket hashed to hash
+func (p *pipeline) recordHash(bucket, hash string) {
+	if p.groupHandler == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.hashSeen[hash] {
+		return
+	}
+	p.hashSeen[hash] = true
+	p.bucketHashes[bucket] = append(p.bucketHashes[bucket], hash)
+}
+
+// flushGroups hands out every group not handed out yet, once a search has
+// finished or been interrupted
+func (p *pipeline) flushGroups() {
+	if p.groupHandler == nil {
+		return
+	}
+	if _, ok := p.activeGrouper.(GroupLookup); !ok {
+		for _, group := range splitGroups(p.duplicates()) {
+			p.groupHandler(group)
+		}
+		return
+	}
+
+	p.emitMu.Lock()
+	defer p.emitMu.Unlock()
+	p.mu.Lock()
+	remaining := append([]string(nil), p.bucketKeys[p.nextBucket:]...)
+	p.nextBucket = len(p.bucketKeys)
+	// An interrupted search can leave hashes in buckets never reached
+	listed := make(map[string]bool, len(remaining))
+	for _, bucket := range remaining {
+		listed[bucket] = true
+	}
+	for bucket := range p.bucketHashes {
+		if !listed[bucket] {
+			remaining = append(remaining, bucket)
+		}
+	}
+	p.mu.Unlock()
+
+	sort.Strings(remaining)
+	for _, bucket := range remaining {
+		p.emitBucket(bucket)
+	}
+}
+
+// emitBucket hands the groups of a first-stage bucket to the handler, sorted
+// by original, and releases them; p.emitMu must be held
+func (p *pipeline) emitBucket(bucket string) {
+	lookup, ok := p.activeGrouper.(GroupLookup)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	hashes := p.bucketHashes[bucket]
+	delete(p.bucketHashes, bucket)
+	for _, hash := range hashes {
+		delete(p.hashSeen, hash)
+	}
+	p.mu.Unlock()
+
+	var groups [][]Duplicate
+	for _, hash := range hashes {
+		// A verified hash can hold several groups, one per distinct content
+		for _, group := range splitGroups(ArrangeDuplicates(lookup.TakeDuplicates(hash), p.originalRule, p.roots)) {
+			groups = append(groups, p.annotate(group))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0].Original < groups[j][0].Original
+	})
+
+	for _, group := range groups {
+		p.mu.Lock()
+		p.handedOut.TotalDuplicates += len(group)
+		p.handedOut.UniqueOriginals++
+		p.handedOut.TotalDuplicateFiles += len(group) + 1
+		for _, dup := range group {
+			p.handedOut.WastedBytes += dup.Size
+			p.handedOut.ReclaimableBytes += dup.Allocated
+		}
+		p.mu.Unlock()
+		p.groupHandler(group)
+	}
+}
+
+// splitGroups splits arranged duplicates into one slice per group
+func splitGroups(duplicates []Duplicate) [][]Duplicate {
+	var groups [][]Duplicate
+	start := 0
+	for i := 1; i <= len(duplicates); i++ {
+		if i == len(duplicates) || duplicates[i].Original != duplicates[start].Original {
+			groups = append(groups, duplicates[start:i])
+			start = i
+		}
+	}
+	return groups
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// failingHasher fails for the files it lists and hashes the rest by content
+type failingHasher struct {
+	failing map[string]bool
+}
+
+func (h failingHasher) Hash(ctx context.Context, file FileEntry) (string, error) {
+	if h.failing[file.Path] {
+		return "", errors.New("unreadable")
+	}
+	return calculateFileHash(ctx, MD5, file.Path)
+}
+
+func TestUnreadableFilesMarkGroupsOfTheirSize(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for name, content := range map[string]string{
+		"a":     "four",
+		"b":     "four",
+		"c":     "four",
+		"d":     "fivee",
+		"e":     "fivee",
+		"other": "6chars",
+		"lost":  "6chars",
+	} {
+		if err := os.WriteFile(path(name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hasher := failingHasher{failing: map[string]bool{path("c"): true, path("lost"): true}}
+
+	for name, finder := range map[string]interface {
+		Scanner
+		SetHasher(Hasher)
+		SetGroupHandler(GroupHandler)
+	}{
+		"sequential": NewDuplicateFinder(MD5, nil),
+		"concurrent": NewConcurrentDuplicateFinder(MD5, nil, 4),
+	} {
+		finder.SetHasher(hasher)
+		for _, streamed := range []bool{false, true} {
+			var duplicates []Duplicate
+			if streamed {
+				finder.SetGroupHandler(func(group []Duplicate) {
+					duplicates = append(duplicates, group...)
+				})
+			} else {
+				finder.SetGroupHandler(nil)
+			}
+			returned, err := finder.SearchDuplicates(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			duplicates = append(duplicates, returned...)
+
+			want := map[string][]string{path("b"): {path("c")}, path("e"): nil}
+			if len(duplicates) != len(want) {
+				t.Fatalf("%s, streamed %v: found %v, want duplicates of a and d", name, streamed, duplicates)
+			}
+			for _, dup := range duplicates {
+				if !reflect.DeepEqual(dup.Unreadable, want[dup.Duplicate]) {
+					t.Errorf("%s, streamed %v: %s lists unreadable files %v, want %v",
+						name, streamed, dup.Duplicate, dup.Unreadable, want[dup.Duplicate])
+				}
+			}
+		}
+	}
+}
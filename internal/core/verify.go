@@ -0,0 +1,55 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// verifyChunkSize is the buffer size used when comparing files byte for byte
+const verifyChunkSize = 64 * 1024
+
+// HashCollision records two files whose hashes matched but whose contents differ
+type HashCollision struct {
+	Original  string `json:"original"`
+	Candidate string `json:"candidate"`
+	Hash      string `json:"hash"`
+}
+
+// FilesEqual reports whether two files have identical contents by streaming
+// both and comparing them chunk by chunk
+func FilesEqual(pathA, pathB string) (bool, error) {
+	fileA, err := os.Open(pathA)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file %s: %w", pathA, err)
+	}
+	defer fileA.Close()
+
+	fileB, err := os.Open(pathB)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file %s: %w", pathB, err)
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, verifyChunkSize)
+	bufB := make([]byte, verifyChunkSize)
+	for {
+		nA, errA := io.ReadFull(fileA, bufA)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, fmt.Errorf("failed to read file %s: %w", pathA, errA)
+		}
+		nB, errB := io.ReadFull(fileB, bufB)
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, fmt.Errorf("failed to read file %s: %w", pathB, errB)
+		}
+
+		if nA != nB || !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		// A short read means both files ended at the same offset
+		if errA != nil {
+			return true, nil
+		}
+	}
+}
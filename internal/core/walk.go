@@ -0,0 +1,286 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// SymlinkPolicy decides what the walker does with symbolic links
+type SymlinkPolicy string
+
+const (
+	// SymlinksSkip ignores symbolic links entirely
+	SymlinksSkip SymlinkPolicy = "skip"
+	// SymlinksReport lists symbolic links, with their targets, without
+	// following them
+	SymlinksReport SymlinkPolicy = "report"
+	// SymlinksFollow treats symbolic links as the files and directories they
+	// point to. Each directory is walked at most once, so link cycles are safe.
+	SymlinksFollow SymlinkPolicy = "follow"
+)
+
+// DefaultSymlinkPolicy is the policy used unless another is set
+const DefaultSymlinkPolicy = SymlinksSkip
+
+// GetSymlinkPolicies returns the supported symlink policies
+func GetSymlinkPolicies() []SymlinkPolicy {
+	return []SymlinkPolicy{SymlinksSkip, SymlinksReport, SymlinksFollow}
+}
+
+// IsValidSymlinkPolicy checks if the given policy is supported
+func IsValidSymlinkPolicy(policy string) bool {
+	for _, supported := range GetSymlinkPolicies() {
+		if SymlinkPolicy(policy) == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkOptions configures the default walker
+type WalkOptions struct {
+	// Rules selects the files and directories to skip; nil excludes nothing
+	Rules *ExcludeRules
+	// RespectIgnore honors the .gitignore and .clonespotterignore files found
+	// in every directory, the .git/info/exclude file of every repository and
+	// the ignore files of any repository enclosing the root. Git metadata
+	// directories are always skipped. Rules take precedence over ignore files.
+	RespectIgnore bool
+	// Symlinks decides how symbolic links are handled; empty means
+	// DefaultSymlinkPolicy
+	Symlinks SymlinkPolicy
+	// OneFileSystem keeps the walk on the filesystem holding the root,
+	// skipping directories that are mount points of any other
+	OneFileSystem bool
+}
+
+// dirWalker walks a directory tree recursively, skipping excluded files and
+// directories
+type dirWalker struct {
+	opts WalkOptions
+}
+
+// NewDirWalker returns the default Walker, which skips the files and
+// directories matched by rules. A nil rules excludes nothing.
+func NewDirWalker(rules *ExcludeRules) Walker {
+	return NewWalker(WalkOptions{Rules: rules})
+}
+
+// NewWalker returns the default Walker configured by opts
+func NewWalker(opts WalkOptions) Walker {
+	if opts.Symlinks == "" {
+		opts.Symlinks = DefaultSymlinkPolicy
+	}
+	return &dirWalker{opts: opts}
+}
+
+// walkState is the state of a single walk
+type walkState struct {
+	opts    WalkOptions
+	visit   func(file FileEntry) error
+	visited map[fileID]bool // Directories entered, when following symlinks
+
+	rootDev      uint64
+	rootDevKnown bool            // Whether rootDev can be compared against
+	absRoot      string          // Absolute root, to compare with mount points
+	pseudoMounts map[string]bool // Pseudo filesystem mount points to skip
+}
+
+// Walk visits every regular file beneath rootDir. FIFOs, sockets and device
+// nodes are skipped without being opened, as are pseudo filesystems such as
+// /proc mounted beneath the root. Unreadable subdirectories are reported as
+// warnings and skipped; an error from visit stops the walk.
+func (w *dirWalker) Walk(rootDir string, visit func(file FileEntry) error) error {
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", rootDir, err)
+	}
+
+	state := &walkState{opts: w.opts, visit: visit}
+	if w.opts.Symlinks == SymlinksFollow {
+		state.visited = make(map[fileID]bool)
+		state.enterDir(rootDir)
+	}
+	if w.opts.OneFileSystem {
+		if info, err := os.Stat(rootDir); err == nil {
+			state.rootDev, _, state.rootDevKnown = FileIdentity(info)
+		}
+	}
+	if absRoot, err := filepath.Abs(rootDir); err == nil {
+		state.absRoot = absRoot
+		state.pseudoMounts = PseudoMounts()
+		// Scanning a pseudo filesystem on purpose is allowed
+		delete(state.pseudoMounts, absRoot)
+	}
+
+	var ignores ignoreStack
+	rootRel := ""
+	if w.opts.RespectIgnore {
+		ignores, rootRel = rootIgnoreStack(rootDir)
+	}
+	return state.walkEntries(rootDir, "", rootRel, entries, ignores)
+}
+
+// enterDir records a directory as visited when following symlinks and
+// reports whether it had not been visited before. Directories whose identity
+// is unknown are always entered.
+func (s *walkState) enterDir(dirPath string) bool {
+	if s.visited == nil {
+		return true
+	}
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return true
+	}
+	dev, ino, ok := FileIdentity(info)
+	if !ok {
+		return true
+	}
+	id := fileID{dev: dev, ino: ino}
+	if s.visited[id] {
+		return false
+	}
+	s.visited[id] = true
+	return true
+}
+
+// crossesMount reports whether a directory should be skipped because it is
+// a pseudo filesystem mount or, with OneFileSystem, lies on another device
+func (s *walkState) crossesMount(fullPath, relPath string, info os.FileInfo) bool {
+	if len(s.pseudoMounts) > 0 && s.pseudoMounts[filepath.Join(s.absRoot, filepath.FromSlash(relPath))] {
+		return true
+	}
+	if !s.rootDevKnown {
+		return false
+	}
+	if info == nil {
+		var err error
+		if info, err = os.Lstat(fullPath); err != nil {
+			return false
+		}
+	}
+	dev, _, ok := FileIdentity(info)
+	return ok && dev != s.rootDev
+}
+
+// skipped reports whether an entry is excluded by the rules or ignore files
+func (s *walkState) skipped(name, relPath, topPath string, isDir bool, ignores ignoreStack) bool {
+	if s.opts.Rules.Excluded(relPath, isDir) {
+		return true
+	}
+	if !s.opts.RespectIgnore {
+		return false
+	}
+	if isDir && name == gitDir {
+		return true
+	}
+	return ignores.ignored(topPath, isDir)
+}
+
+// walkEntries recursively processes the entries of a directory. relDir is
+// the directory's path relative to the root, used for exclusion matching,
+// and topDir its path relative to the top of the enclosing repository, used
+// for ignore-file matching.
+func (s *walkState) walkEntries(dirPath, relDir, topDir string, entries []os.DirEntry, ignores ignoreStack) error {
+	for _, entry := range entries {
+		fullPath := filepath.Join(dirPath, entry.Name())
+		relPath := path.Join(relDir, entry.Name())
+		topPath := path.Join(topDir, entry.Name())
+
+		isDir := entry.IsDir()
+		var info os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 {
+			switch s.opts.Symlinks {
+			case SymlinksSkip:
+				continue
+			case SymlinksReport:
+				if s.skipped(entry.Name(), relPath, topPath, false, ignores) {
+					continue
+				}
+				if err := s.reportSymlink(fullPath); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Follow the link to whatever it points at
+			target, err := os.Stat(fullPath)
+			if err != nil {
+				// Log warning but continue processing
+				fmt.Fprintf(os.Stderr, "Warning: failed to follow symlink %s: %v\n", fullPath, err)
+				continue
+			}
+			isDir, info = target.IsDir(), target
+		}
+
+		if isDir {
+			if s.skipped(entry.Name(), relPath, topPath, true, ignores) {
+				continue
+			}
+			if s.crossesMount(fullPath, relPath, info) {
+				continue
+			}
+			if !s.enterDir(fullPath) {
+				// Reached again through a symlink; walking it twice would
+				// report every file in it as its own duplicate
+				continue
+			}
+			subEntries, err := os.ReadDir(fullPath)
+			if err != nil {
+				// Log warning but continue processing
+				fmt.Fprintf(os.Stderr, "Warning: failed to read directory %s: %v\n", fullPath, err)
+				continue
+			}
+			subIgnores := ignores
+			if s.opts.RespectIgnore {
+				subIgnores = ignores.enter(fullPath, topPath)
+			}
+			if err := s.walkEntries(fullPath, relPath, topPath, subEntries, subIgnores); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if s.skipped(entry.Name(), relPath, topPath, false, ignores) {
+			continue
+		}
+
+		if info == nil {
+			var err error
+			if info, err = entry.Info(); err != nil {
+				// Log warning but continue processing
+				fmt.Fprintf(os.Stderr, "Warning: failed to stat file %s: %v\n", fullPath, err)
+				continue
+			}
+		}
+		if !info.Mode().IsRegular() {
+			// FIFOs, sockets and device nodes can block or never end when read
+			continue
+		}
+		if err := s.visit(NewFileEntry(fullPath, info)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportSymlink passes an unfollowed symbolic link to visit, with its target
+func (s *walkState) reportSymlink(linkPath string) error {
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to stat symlink %s: %v\n", linkPath, err)
+		return nil
+	}
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read symlink %s: %v\n", linkPath, err)
+		return nil
+	}
+
+	file := NewFileEntry(linkPath, info)
+	file.SymlinkTarget = target
+	return s.visit(file)
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DelimitedHeader is the header row of CSV and TSV reports
+var DelimitedHeader = []string{"group_id", "hash", "size", "path", "role", "mtime"}
+
+// WriteDelimited writes one row per grouped file, fields separated by comma,
+// after a header row. Fields holding the separator, quotes or line breaks are
+// quoted, so any path survives a round trip through a spreadsheet.
+func (r *Report) WriteDelimited(w io.Writer, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(DelimitedHeader); err != nil {
+		return err
+	}
+	for _, group := range r.Groups {
+		for _, file := range group.Files {
+			record := []string{
+				strconv.Itoa(group.ID),
+				group.Hash,
+				strconv.FormatInt(group.Size, 10),
+				file.Path,
+				file.Role,
+				file.ModTime.Format(time.RFC3339Nano),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteDelimitedRoundTrip(t *testing.T) {
+	modTime := time.Date(2024, 3, 15, 12, 30, 0, 123456789, time.UTC)
+	paths := []string{
+		"/data/plain.txt",
+		"/data/with, comma.txt",
+		`/data/with "quotes".txt`,
+		"/data/with\ttab.txt",
+		"/data/with\nnewline.txt",
+		` /data/"all", of	them
+.txt`,
+	}
+	files := make([]File, len(paths))
+	for i, path := range paths {
+		role := RoleDuplicate
+		if i == 0 {
+			role = RoleOriginal
+		}
+		files[i] = File{Path: path, Role: role, ModTime: modTime}
+	}
+	r := &Report{Groups: []Group{{ID: 7, Hash: "b1946ac92492d2347c6235b4d2611184", Size: 6, Files: files}}}
+
+	for _, comma := range []rune{',', '\t'} {
+		var buf bytes.Buffer
+		if err := r.WriteDelimited(&buf, comma); err != nil {
+			t.Fatal(err)
+		}
+
+		cr := csv.NewReader(&buf)
+		cr.Comma = comma
+		records, err := cr.ReadAll()
+		if err != nil {
+			t.Fatalf("separator %q: reading the output back: %v", comma, err)
+		}
+		if len(records) != len(paths)+1 {
+			t.Fatalf("separator %q: read %d records, want a header and %d rows", comma, len(records), len(paths))
+		}
+		if !reflect.DeepEqual(records[0], DelimitedHeader) {
+			t.Errorf("separator %q: header = %q, want %q", comma, records[0], DelimitedHeader)
+		}
+		for i, record := range records[1:] {
+			want := []string{"7", "b1946ac92492d2347c6235b4d2611184", "6", paths[i], files[i].Role, modTime.Format(time.RFC3339Nano)}
+			if len(record) != len(DelimitedHeader) {
+				t.Errorf("separator %q: row %d has %d fields, want %d: %q", comma, i+1, len(record), len(DelimitedHeader), record)
+				continue
+			}
+			if !reflect.DeepEqual(record, want) {
+				t.Errorf("separator %q: row %d = %q, want %q", comma, i+1, record, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Format is an output format for reports
+type Format string
+
+const (
+	// FormatJSON writes the whole report as one JSON document
+	FormatJSON Format = "json"
+	// FormatCSV writes one comma-separated row per grouped file
+	FormatCSV Format = "csv"
+	// FormatTSV writes one tab-separated row per grouped file
+	FormatTSV Format = "tsv"
+	// FormatNDJSON writes one JSON record per line, streaming groups as they
+	// are found
+	FormatNDJSON Format = "ndjson"
+	// FormatHTML writes a self-contained page for browsing the results
+	FormatHTML Format = "html"
+	// FormatSQLite adds the report to a SQLite database, which can hold many
+	// scans; it is written with WriteSQLite rather than encoded
+	FormatSQLite Format = "sqlite"
+	// FormatScript writes a shell script with a cleanup command for every
+	// duplicate, to review before running
+	FormatScript Format = "script"
+)
+
+// DefaultFormat is the format used unless another is set
+const DefaultFormat = FormatJSON
+
+// GetFormats returns the supported output formats
+func GetFormats() []Format {
+	return []Format{FormatJSON, FormatCSV, FormatTSV, FormatNDJSON, FormatHTML, FormatSQLite, FormatScript}
+}
+
+// IsValidFormat checks if the given format is supported
+func IsValidFormat(format string) bool {
+	for _, supported := range GetFormats() {
+		if Format(format) == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Extension returns the file extension for the format, including the dot
+func (f Format) Extension() string {
+	if f == FormatScript {
+		return ".sh"
+	}
+	return "." + string(f)
+}
+
+// Encode renders the report in the given format
+func (r *Report) Encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+	case FormatCSV, FormatTSV:
+		comma := ','
+		if format == FormatTSV {
+			comma = '\t'
+		}
+		var buf bytes.Buffer
+		if err := r.WriteDelimited(&buf, comma); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatNDJSON:
+		var buf bytes.Buffer
+		if err := r.WriteNDJSON(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatHTML:
+		var buf bytes.Buffer
+		if err := r.WriteHTML(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatScript:
+		var buf bytes.Buffer
+		if err := r.WriteScript(&buf, DefaultScriptAction, false); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatSQLite:
+		return nil, fmt.Errorf("the %s format is written to a database, not encoded", format)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
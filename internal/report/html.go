@@ -0,0 +1,87 @@
+package report
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"clone-spotter/internal/utils"
+)
+
+// htmlAssets holds the page template and the stylesheet and script inlined
+// into it, so an HTML report is a single file that opens anywhere
+//
+//go:embed html
+var htmlAssets embed.FS
+
+var htmlTemplate = template.Must(template.New("report.html.tmpl").Funcs(template.FuncMap{
+	"size": utils.FormatFileSize,
+}).ParseFS(htmlAssets, "html/report.html.tmpl"))
+
+// DirectoryStats totals the duplicates found in one directory
+type DirectoryStats struct {
+	Path        string
+	Duplicates  int
+	WastedBytes int64
+}
+
+// htmlPage is what the HTML template renders
+type htmlPage struct {
+	*Report
+	Directories []DirectoryStats
+	CSS         template.CSS
+	JS          template.JS
+}
+
+// WriteHTML renders the report as a self-contained HTML page with the
+// summary, the groups and a breakdown of wasted space by directory
+func (r *Report) WriteHTML(w io.Writer) error {
+	css, err := htmlAssets.ReadFile("html/report.css")
+	if err != nil {
+		return err
+	}
+	js, err := htmlAssets.ReadFile("html/report.js")
+	if err != nil {
+		return err
+	}
+
+	return htmlTemplate.Execute(w, htmlPage{
+		Report:      r,
+		Directories: r.Directories(),
+		CSS:         template.CSS(css),
+		JS:          template.JS(js),
+	})
+}
+
+// Directories totals the duplicates of the report by the directory holding
+// them, the directory wasting the most space first. Ties are broken by path.
+func (r *Report) Directories() []DirectoryStats {
+	index := make(map[string]int)
+	var dirs []DirectoryStats
+	for _, group := range r.Groups {
+		for _, file := range group.Files {
+			if file.Role != RoleDuplicate {
+				continue
+			}
+			dir := filepath.Dir(file.Path)
+			i, exists := index[dir]
+			if !exists {
+				i = len(dirs)
+				index[dir] = i
+				dirs = append(dirs, DirectoryStats{Path: dir})
+			}
+			dirs[i].Duplicates++
+			dirs[i].WastedBytes += group.Size
+		}
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].WastedBytes != dirs[j].WastedBytes {
+			return dirs[i].WastedBytes > dirs[j].WastedBytes
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
@@ -0,0 +1,73 @@
+(function () {
+  "use strict";
+
+  var list = document.getElementById("group-list");
+  var groups = list ? Array.prototype.slice.call(list.querySelectorAll(".group")) : [];
+  var directories = Array.prototype.slice.call(document.querySelectorAll("tr.directory"));
+  var search = document.getElementById("search");
+  var matches = document.getElementById("matches");
+
+  // Orderings offered by the sort menu; every one falls back to group number
+  var orders = {
+    "wasted-desc": function (a, b) { return num(b, "wasted") - num(a, "wasted"); },
+    "wasted-asc": function (a, b) { return num(a, "wasted") - num(b, "wasted"); },
+    "files-desc": function (a, b) { return num(b, "files") - num(a, "files"); },
+    "id-asc": function () { return 0; }
+  };
+
+  // Only paths and hashes are searched, not the labels around them
+  function searchText(el) {
+    var parts = el.querySelectorAll("code, .hash");
+    return Array.prototype.map.call(parts, function (part) {
+      return part.textContent;
+    }).join("\n").toLowerCase();
+  }
+  var texts = new Map();
+  groups.concat(directories).forEach(function (el) { texts.set(el, searchText(el)); });
+
+  function num(el, key) {
+    return Number(el.getAttribute("data-" + key));
+  }
+
+  function sortGroups(order) {
+    var compare = orders[order] || orders["wasted-desc"];
+    groups.sort(function (a, b) {
+      return compare(a, b) || num(a, "id") - num(b, "id");
+    });
+    groups.forEach(function (group) { list.appendChild(group); });
+  }
+
+  function filter() {
+    var query = search.value.trim().toLowerCase();
+    var shown = 0;
+    groups.forEach(function (group) {
+      var match = !query || texts.get(group).indexOf(query) !== -1;
+      group.classList.toggle("hidden", !match);
+      if (match) {
+        shown++;
+      }
+    });
+    directories.forEach(function (row) {
+      var match = !query || texts.get(row).indexOf(query) !== -1;
+      row.classList.toggle("hidden", !match);
+    });
+    matches.textContent = query ? shown + " of " + groups.length + " groups" : "";
+  }
+
+  function setOpen(open) {
+    groups.forEach(function (group) {
+      if (!group.classList.contains("hidden")) {
+        group.open = open;
+      }
+    });
+  }
+
+  search.addEventListener("input", filter);
+  if (list) {
+    var sort = document.getElementById("sort");
+    sort.addEventListener("change", function () { sortGroups(sort.value); });
+    document.getElementById("expand").addEventListener("click", function () { setOpen(true); });
+    document.getElementById("collapse").addEventListener("click", function () { setOpen(false); });
+    sortGroups(sort.value);
+  }
+})();
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"clone-spotter/internal/utils"
+)
+
+func TestWriteHTMLEscapesPaths(t *testing.T) {
+	const dir = "/data/<script>alert(1)</script> & co"
+	modTime := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	r := &Report{
+		Tool: Tool{Name: "test", Version: "0"},
+		Scan: Scan{Roots: []string{dir}, Algorithm: "md5", OriginalRule: "path", StartedAt: modTime},
+		Summary: Summary{
+			FilesScanned:     12,
+			FilesHashed:      4,
+			DuplicateGroups:  1,
+			DuplicateFiles:   1,
+			WastedBytes:      3 << 20,
+			ReclaimableBytes: 2 << 20,
+		},
+		Groups: []Group{{
+			ID:          1,
+			Hash:        "b1946ac92492d2347c6235b4d2611184",
+			Size:        3 << 20,
+			WastedBytes: 3 << 20,
+			Files: []File{
+				{Path: dir + "/a", Root: dir, Role: RoleOriginal, ModTime: modTime},
+				{Path: dir + "/b", Root: dir, Role: RoleDuplicate, ModTime: modTime},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	if strings.Contains(page, "<script>alert(1)") {
+		t.Error("path written unescaped")
+	}
+	if escaped := "/data/&lt;script&gt;alert(1)&lt;/script&gt; &amp; co/b"; !strings.Contains(page, escaped) {
+		t.Errorf("page lacks the escaped path %q", escaped)
+	}
+
+	for _, want := range []string{
+		"<dt>Wasted space</dt><dd>" + utils.FormatFileSize(3<<20) + "</dd>",
+		"<dt>Reclaimable on disk</dt><dd>" + utils.FormatFileSize(2<<20) + "</dd>",
+		"<dt>Duplicate groups</dt><dd>1</dd>",
+		"<dt>Duplicate files</dt><dd>1</dd>",
+		"<dt>Files scanned</dt><dd>12</dd>",
+		"<dt>Files hashed</dt><dd>4</dd>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks the summary line %q", want)
+		}
+	}
+}
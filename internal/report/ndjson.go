@@ -0,0 +1,149 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"clone-spotter/internal/core"
+)
+
+// Record types of NDJSON reports. Every line is a JSON object whose "type"
+// field says which of these it is.
+const (
+	// RecordScan is the first line: the schema version, tool and scan
+	// parameters
+	RecordScan = "scan"
+	// RecordGroup is a duplicate group, written as soon as it is complete;
+	// its other fields are those of Group
+	RecordGroup = "group"
+	// RecordSummary is the last line: when the search finished, whether it
+	// was interrupted, its totals and the files listed outside the groups
+	RecordSummary = "summary"
+)
+
+type scanRecord struct {
+	Type          string `json:"type"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Tool          Tool   `json:"tool"`
+	Scan          struct {
+		Scan
+		// Not known yet; shadow the Scan fields so they are left out
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+		Incomplete *bool      `json:"incomplete,omitempty"`
+	} `json:"scan"`
+}
+
+type groupRecord struct {
+	Type string `json:"type"`
+	Group
+}
+
+type summaryRecord struct {
+	Type           string               `json:"type"`
+	FinishedAt     time.Time            `json:"finishedAt"`
+	Incomplete     bool                 `json:"incomplete"`
+	Summary        Summary              `json:"summary"`
+	EmptyFiles     []string             `json:"emptyFiles"`
+	HardLinks      []HardLinkGroup      `json:"hardLinks"`
+	Symlinks       []core.Symlink       `json:"symlinks"`
+	HashCollisions []core.HashCollision `json:"hashCollisions"`
+	Errors         []core.FileError     `json:"errors"`
+}
+
+// StreamWriter writes a report as newline-delimited JSON, one record per
+// line, so groups can be consumed while a search is still running. It is
+// safe for concurrent use.
+type StreamWriter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	nextID int
+	err    error
+}
+
+// NewStreamWriter returns a StreamWriter writing to w. Every record is
+// written to w as a whole, so w should not buffer if lines are to be seen
+// as they are written.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{enc: json.NewEncoder(w), nextID: 1}
+}
+
+// WriteScan writes the scan record, which must come first
+func (s *StreamWriter) WriteScan(tool Tool, scan Scan) error {
+	record := scanRecord{Type: RecordScan, SchemaVersion: SchemaVersion, Tool: tool}
+	record.Scan.Scan = normalizeScan(scan)
+	return s.write(record)
+}
+
+// WriteGroup writes one duplicate group, numbering groups in the order they
+// are written
+func (s *StreamWriter) WriteGroup(group []core.Duplicate) error {
+	return s.writeGroups(Groups(group))
+}
+
+// writeGroups writes groups, renumbering them in the order they are written
+func (s *StreamWriter) writeGroups(groups []Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range groups {
+		g.ID = s.nextID
+		s.nextID++
+		if err := s.encode(groupRecord{Type: RecordGroup, Group: g}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSummary writes the summary record, which must come last. Everything
+// but the groups of r is written; they are expected to have been written
+// with WriteGroup already.
+func (s *StreamWriter) WriteSummary(r *Report) error {
+	return s.write(summaryRecord{
+		Type:           RecordSummary,
+		FinishedAt:     r.Scan.FinishedAt,
+		Incomplete:     r.Scan.Incomplete,
+		Summary:        r.Summary,
+		EmptyFiles:     r.EmptyFiles,
+		HardLinks:      r.HardLinks,
+		Symlinks:       r.Symlinks,
+		HashCollisions: r.HashCollisions,
+		Errors:         r.Errors,
+	})
+}
+
+// WriteNDJSON writes the whole report as newline-delimited JSON, in the same
+// records a StreamWriter produces
+func (r *Report) WriteNDJSON(w io.Writer) error {
+	s := NewStreamWriter(w)
+	if err := s.WriteScan(r.Tool, r.Scan); err != nil {
+		return err
+	}
+	if err := s.writeGroups(r.Groups); err != nil {
+		return err
+	}
+	return s.WriteSummary(r)
+}
+
+// Err returns the first error met while writing
+func (s *StreamWriter) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *StreamWriter) write(record interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encode(record)
+}
+
+// encode writes one record unless an earlier write failed; s.mu must be held
+func (s *StreamWriter) encode(record interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.err = s.enc.Encode(record)
+	return s.err
+}
@@ -0,0 +1,247 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"clone-spotter/internal/core"
+)
+
+// SchemaVersion is the version of the report layout. It changes whenever a
+// field is removed or its meaning changes; new optional fields keep it.
+const SchemaVersion = 1
+
+// Role values for File.Role
+const (
+	RoleOriginal  = "original"
+	RoleDuplicate = "duplicate"
+)
+
+// Report is the versioned description of one search, written as JSON. Its
+// layout is published in schema.json.
+type Report struct {
+	SchemaVersion  int                  `json:"schemaVersion"`
+	Tool           Tool                 `json:"tool"`
+	Scan           Scan                 `json:"scan"`
+	Summary        Summary              `json:"summary"`
+	Groups         []Group              `json:"groups"`
+	EmptyFiles     []string             `json:"emptyFiles"`
+	HardLinks      []HardLinkGroup      `json:"hardLinks"`
+	Symlinks       []core.Symlink       `json:"symlinks"`
+	HashCollisions []core.HashCollision `json:"hashCollisions"`
+	Errors         []core.FileError     `json:"errors"`
+}
+
+// Tool identifies the program that wrote a report
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Scan records the parameters of a search and when it ran. Incomplete is
+// set for searches that were interrupted.
+type Scan struct {
+	Roots         []string  `json:"roots"`
+	Algorithm     string    `json:"algorithm"`
+	ExcludedDirs  []string  `json:"excludedDirs"`
+	ExcludedFiles []string  `json:"excludedFiles"`
+	RespectIgnore bool      `json:"respectIgnore"`
+	Filters       Filters   `json:"filters"`
+	EmptyFiles    string    `json:"emptyFiles"`
+	Symlinks      string    `json:"symlinks"`
+	OneFileSystem bool      `json:"oneFileSystem"`
+	OriginalRule  string    `json:"originalRule"`
+	Verify        bool      `json:"verify"`
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+	Incomplete    bool      `json:"incomplete"`
+}
+
+// Filters are the file selection filters of a search; unset ones are omitted
+type Filters struct {
+	MinSize    int64      `json:"minSize,omitempty"`
+	MaxSize    int64      `json:"maxSize,omitempty"`
+	IncludeExt []string   `json:"includeExt,omitempty"`
+	ExcludeExt []string   `json:"excludeExt,omitempty"`
+	Names      []string   `json:"names,omitempty"`
+	NewerThan  *time.Time `json:"newerThan,omitempty"`
+	OlderThan  *time.Time `json:"olderThan,omitempty"`
+}
+
+// NewFilters converts core filter options into their report form
+func NewFilters(opts core.FilterOptions) Filters {
+	filters := Filters{
+		MinSize:    opts.MinSize,
+		MaxSize:    opts.MaxSize,
+		IncludeExt: opts.IncludeExt,
+		ExcludeExt: opts.ExcludeExt,
+		Names:      opts.Names,
+	}
+	if !opts.NewerThan.IsZero() {
+		newerThan := opts.NewerThan
+		filters.NewerThan = &newerThan
+	}
+	if !opts.OlderThan.IsZero() {
+		olderThan := opts.OlderThan
+		filters.OlderThan = &olderThan
+	}
+	return filters
+}
+
+// Summary holds the totals of a search
+type Summary struct {
+	FilesScanned     int   `json:"filesScanned"`
+	FilesFiltered    int   `json:"filesFiltered"`
+	FilesHashed      int   `json:"filesHashed"`
+	CacheHits        int   `json:"cacheHits"`
+	DuplicateGroups  int   `json:"duplicateGroups"`
+	DuplicateFiles   int   `json:"duplicateFiles"`
+	EmptyFiles       int   `json:"emptyFiles"`
+	HardLinks        int   `json:"hardLinks"`
+	Symlinks         int   `json:"symlinks"`
+	HashCollisions   int   `json:"hashCollisions"`
+	Errors           int   `json:"errors"`
+	WastedBytes      int64 `json:"wastedBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+// Group is a set of files with identical content. The first file is the
+// original and the rest are its duplicates.
+type Group struct {
+	ID               int    `json:"id"`
+	Hash             string `json:"hash"`
+	Size             int64  `json:"size"`
+	WastedBytes      int64  `json:"wastedBytes"`
+	ReclaimableBytes int64  `json:"reclaimableBytes"`
+	CrossRoot        bool   `json:"crossRoot"`
+	Files            []File `json:"files"`
+	// Unreadable lists the files of the group's size that could not be read
+	// and may belong to it. It is left out when there are none.
+	Unreadable []string `json:"unreadable,omitempty"`
+}
+
+// File is a member of a duplicate group
+type File struct {
+	Path    string    `json:"path"`
+	Root    string    `json:"root"`
+	Role    string    `json:"role"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// HardLinkGroup lists the paths that link to one file. Removing a link frees
+// no space, which ReclaimableBytes makes explicit.
+type HardLinkGroup struct {
+	Path             string   `json:"path"`
+	Links            []string `json:"links"`
+	ReclaimableBytes int64    `json:"reclaimableBytes"`
+}
+
+// HardLinkGroups converts the hard links found by a search into report
+// groups, sorted by path
+func HardLinkGroups(links map[string][]string) []HardLinkGroup {
+	groups := make([]HardLinkGroup, 0, len(links))
+	for path, paths := range links {
+		groups = append(groups, HardLinkGroup{Path: path, Links: paths})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Path < groups[j].Path
+	})
+	return groups
+}
+
+// Groups gathers duplicate pairs into groups, in the order their originals
+// were first reported, numbering them from 1. Scanners report groups sorted
+// by original path, so IDs are stable from one run to the next.
+func Groups(duplicates []core.Duplicate) []Group {
+	groups := make([]Group, 0)
+	index := make(map[string]int)
+	for _, dup := range duplicates {
+		i, exists := index[dup.Original]
+		if !exists {
+			i = len(groups)
+			index[dup.Original] = i
+			groups = append(groups, Group{
+				ID:         i + 1,
+				Hash:       dup.Hash,
+				Size:       dup.Size,
+				Unreadable: dup.Unreadable,
+				Files: []File{{
+					Path:    dup.Original,
+					Root:    dup.OriginalRoot,
+					Role:    RoleOriginal,
+					ModTime: modTime(dup.OriginalModTime),
+				}},
+			})
+		}
+
+		group := &groups[i]
+		group.WastedBytes += dup.Size
+		group.ReclaimableBytes += dup.Allocated
+		group.CrossRoot = group.CrossRoot || dup.CrossRoot()
+		group.Files = append(group.Files, File{
+			Path:    dup.Duplicate,
+			Root:    dup.DuplicateRoot,
+			Role:    RoleDuplicate,
+			ModTime: modTime(dup.DuplicateModTime),
+		})
+	}
+	return groups
+}
+
+// New builds the report of a finished or interrupted search from the
+// duplicates it returned and the state scanner kept about it
+func New(tool Tool, scan Scan, duplicates []core.Duplicate, scanner core.Scanner) *Report {
+	return &Report{
+		SchemaVersion:  SchemaVersion,
+		Tool:           tool,
+		Scan:           normalizeScan(scan),
+		Summary:        NewSummary(scanner),
+		Groups:         Groups(duplicates),
+		EmptyFiles:     orEmpty(scanner.EmptyFiles()),
+		HardLinks:      HardLinkGroups(scanner.HardLinks()),
+		Symlinks:       orEmpty(scanner.Symlinks()),
+		HashCollisions: orEmpty(scanner.Collisions()),
+		Errors:         orEmpty(scanner.Errors()),
+	}
+}
+
+// NewSummary totals the last search of scanner
+func NewSummary(scanner core.Scanner) Summary {
+	stats := scanner.Stats()
+	return Summary{
+		FilesScanned:     stats.FilesScanned,
+		FilesFiltered:    stats.FilesFiltered,
+		FilesHashed:      stats.FilesHashed,
+		CacheHits:        stats.CacheHits,
+		DuplicateGroups:  stats.UniqueOriginals,
+		DuplicateFiles:   stats.TotalDuplicates,
+		EmptyFiles:       stats.EmptyFiles,
+		HardLinks:        stats.HardLinks,
+		Symlinks:         stats.Symlinks,
+		HashCollisions:   stats.HashCollisions,
+		Errors:           len(scanner.Errors()),
+		WastedBytes:      stats.WastedBytes,
+		ReclaimableBytes: stats.ReclaimableBytes,
+	}
+}
+
+// normalizeScan replaces nil lists with empty ones
+func normalizeScan(scan Scan) Scan {
+	scan.ExcludedDirs = orEmpty(scan.ExcludedDirs)
+	scan.ExcludedFiles = orEmpty(scan.ExcludedFiles)
+	return scan
+}
+
+// modTime converts Unix nanoseconds to a UTC time
+func modTime(nanos int64) time.Time {
+	return time.Unix(0, nanos).UTC()
+}
+
+// orEmpty returns an empty slice in place of nil, so that lists are always
+// written as arrays rather than null
+func orEmpty[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
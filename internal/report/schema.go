@@ -0,0 +1,8 @@
+package report
+
+import _ "embed"
+
+// Schema is the JSON Schema describing Report
+//
+//go:embed schema.json
+var Schema []byte
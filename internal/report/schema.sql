@@ -0,0 +1,76 @@
+-- Tables of SQLite reports. Every search adds one row to scans; the files it
+-- grouped and their groups reference that row, so one database can hold the
+-- results of many searches. Hashes are shared between scans.
+
+CREATE TABLE IF NOT EXISTS scans (
+    id                INTEGER PRIMARY KEY,
+    tool              TEXT    NOT NULL,
+    tool_version      TEXT    NOT NULL,
+    schema_version    INTEGER NOT NULL,
+    roots             TEXT    NOT NULL, -- JSON array
+    algorithm         TEXT    NOT NULL,
+    original_rule     TEXT    NOT NULL,
+    parameters        TEXT    NOT NULL, -- The report's scan object, as JSON
+    started_at        TEXT    NOT NULL,
+    finished_at       TEXT    NOT NULL,
+    incomplete        INTEGER NOT NULL,
+    files_scanned     INTEGER NOT NULL,
+    files_hashed      INTEGER NOT NULL,
+    duplicate_groups  INTEGER NOT NULL,
+    duplicate_files   INTEGER NOT NULL,
+    wasted_bytes      INTEGER NOT NULL,
+    reclaimable_bytes INTEGER NOT NULL,
+    errors            INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS hashes (
+    id        INTEGER PRIMARY KEY,
+    algorithm TEXT    NOT NULL,
+    digest    TEXT    NOT NULL,
+    size      INTEGER NOT NULL,
+    UNIQUE (algorithm, digest, size)
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id                INTEGER PRIMARY KEY,
+    scan_id           INTEGER NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    number            INTEGER NOT NULL, -- The group's id in the JSON report
+    hash_id           INTEGER NOT NULL REFERENCES hashes (id),
+    wasted_bytes      INTEGER NOT NULL,
+    reclaimable_bytes INTEGER NOT NULL,
+    cross_root        INTEGER NOT NULL,
+    UNIQUE (scan_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS files (
+    id        INTEGER PRIMARY KEY,
+    scan_id   INTEGER NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    group_id  INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    path      TEXT    NOT NULL,
+    directory TEXT    NOT NULL,
+    name      TEXT    NOT NULL,
+    root      TEXT    NOT NULL,
+    role      TEXT    NOT NULL CHECK (role IN ('original', 'duplicate')),
+    mod_time  TEXT    NOT NULL,
+    UNIQUE (scan_id, path)
+);
+
+CREATE INDEX IF NOT EXISTS groups_hash ON groups (hash_id);
+CREATE INDEX IF NOT EXISTS files_group ON files (group_id, role);
+CREATE INDEX IF NOT EXISTS files_directory ON files (scan_id, directory);
+CREATE INDEX IF NOT EXISTS files_path ON files (path);
+
+-- Duplicated bytes shared by every pair of directories, per scan: for each
+-- duplicate, the directory of its original and its own directory
+CREATE VIEW IF NOT EXISTS directory_pairs AS
+SELECT d.scan_id           AS scan_id,
+       o.directory         AS original_directory,
+       d.directory         AS duplicate_directory,
+       COUNT(*)            AS files,
+       SUM(h.size)         AS wasted_bytes
+FROM files d
+JOIN files o  ON o.group_id = d.group_id AND o.role = 'original'
+JOIN groups g ON g.id = d.group_id
+JOIN hashes h ON h.id = g.hash_id
+WHERE d.role = 'duplicate'
+GROUP BY d.scan_id, o.directory, d.directory;
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"clone-spotter/internal/core"
+	"clone-spotter/internal/utils"
+)
+
+// ScriptAction is what a cleanup script does with each duplicate
+type ScriptAction string
+
+const (
+	// ScriptRemove deletes the duplicate
+	ScriptRemove ScriptAction = "rm"
+	// ScriptLink replaces the duplicate with a hard link to its original,
+	// which keeps the path but frees the space. Both files must be on the
+	// same filesystem.
+	ScriptLink ScriptAction = "ln"
+)
+
+// DefaultScriptAction is the action used unless another is set
+const DefaultScriptAction = ScriptRemove
+
+// GetScriptActions returns the supported cleanup script actions
+func GetScriptActions() []ScriptAction {
+	return []ScriptAction{ScriptRemove, ScriptLink}
+}
+
+// IsValidScriptAction checks if the given action is supported
+func IsValidScriptAction(action string) bool {
+	for _, supported := range GetScriptActions() {
+		if ScriptAction(action) == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnverifiedScript is returned by WriteScript for searches that used a
+// non-cryptographic hash without verifying duplicates byte for byte, where a
+// hash collision would put a unique file in the script
+var ErrUnverifiedScript = errors.New("cleanup scripts need a cryptographic hash algorithm or --verify")
+
+// WriteScript writes a POSIX shell script with one command per duplicate,
+// grouped under its original, for review before anything is touched. Unless
+// active is set every command is commented out. Paths are single-quoted and
+// newlines in them are spelled as "$nl", so no file name can end a comment
+// or inject a command. Relative paths are made absolute against the working
+// directory, so the script can be run from anywhere. Reports of unverified
+// searches with a non-cryptographic algorithm are refused with
+// ErrUnverifiedScript.
+func (r *Report) WriteScript(w io.Writer, action ScriptAction, active bool) error {
+	if !r.Scan.Verify && !core.IsCryptographic(core.HashAlgorithm(r.Scan.Algorithm)) {
+		return ErrUnverifiedScript
+	}
+
+	bw := bufio.NewWriter(w)
+	prefix := "# "
+	if active {
+		prefix = ""
+	}
+
+	fmt.Fprintln(bw, "#!/bin/sh")
+	fmt.Fprintf(bw, "# Duplicate cleanup script written by %s %s\n", r.Tool.Name, r.Tool.Version)
+	fmt.Fprintln(bw, "#")
+	for _, root := range r.Scan.Roots {
+		fmt.Fprintf(bw, "# Searched:    %s\n", shellQuote(root))
+	}
+	fmt.Fprintf(bw, "# Finished:    %s\n", r.Scan.FinishedAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(bw, "# Algorithm:   %s, originals chosen by %s\n", r.Scan.Algorithm, r.Scan.OriginalRule)
+	fmt.Fprintf(bw, "# Groups:      %d, with %d duplicates\n", r.Summary.DuplicateGroups, r.Summary.DuplicateFiles)
+	fmt.Fprintf(bw, "# Wasted:      %s, %s reclaimable on disk\n",
+		utils.FormatFileSize(r.Summary.WastedBytes), utils.FormatFileSize(r.Summary.ReclaimableBytes))
+	if r.Scan.Incomplete {
+		fmt.Fprintln(bw, "# WARNING: the search was interrupted, so some duplicates are missing.")
+	}
+	if r.Summary.HashCollisions > 0 {
+		fmt.Fprintf(bw, "# WARNING: %d hash collisions were found and left out.\n", r.Summary.HashCollisions)
+	}
+	fmt.Fprintln(bw, "#")
+	switch action {
+	case ScriptLink:
+		fmt.Fprintln(bw, "# Each command replaces a duplicate with a hard link to its original.")
+	default:
+		fmt.Fprintln(bw, "# Each command deletes a duplicate; its original is kept.")
+	}
+	if active {
+		fmt.Fprintln(bw, "# The commands are active: review them, then run this script with sh.")
+	} else {
+		fmt.Fprintln(bw, "# The commands are commented out: uncomment the ones to run, then run")
+		fmt.Fprintln(bw, "# this script with sh.")
+	}
+	fmt.Fprintln(bw, "# The script stops at the first command that fails.")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "set -eu")
+	fmt.Fprintln(bw, "nl='")
+	fmt.Fprintln(bw, "'")
+
+	for _, group := range r.Groups {
+		if len(group.Files) == 0 {
+			continue
+		}
+		original, err := filepath.Abs(group.Files[0].Path)
+		if err != nil {
+			return err
+		}
+		comment := fmt.Sprintf("%s, %s %s", utils.FormatFileSize(group.Size), r.Scan.Algorithm, group.Hash)
+
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "# Group %d: %s wasted\n", group.ID, utils.FormatFileSize(group.WastedBytes))
+		fmt.Fprintf(bw, "# Keep %s  # %s\n", shellQuote(original), comment)
+		for _, file := range group.Files[1:] {
+			duplicate, err := filepath.Abs(file.Path)
+			if err != nil {
+				return err
+			}
+			switch action {
+			case ScriptLink:
+				fmt.Fprintf(bw, "%sln -f -- %s %s  # %s\n", prefix, shellQuote(original), shellQuote(duplicate), comment)
+			default:
+				fmt.Fprintf(bw, "%srm -- %s  # %s\n", prefix, shellQuote(duplicate), comment)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// shellQuote quotes s as a single POSIX shell word. Single quotes are closed
+// around an escaped quote and newlines are spelled "$nl", which the script
+// header defines, so the result always fits on one line.
+func shellQuote(s string) string {
+	s = strings.ReplaceAll(s, "'", `'\''`)
+	s = strings.ReplaceAll(s, "\n", `'"$nl"'`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,131 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var shellQuoteTests = []struct {
+	in   string
+	want string
+}{
+	{"", "''"},
+	{"plain", "'plain'"},
+	{"with space", "'with space'"},
+	{"it's", `'it'\''s'`},
+	{"''", `''\'''\'''`},
+	{"a\nb", `'a'"$nl"'b'`},
+	{"$HOME `id` $(id) * ? ; & | > <", "'$HOME `id` $(id) * ? ; & | > <'"},
+	{`back\slash "double"`, `'back\slash "double"'`},
+	{"-rf", "'-rf'"},
+	{"# not a comment", "'# not a comment'"},
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, tt := range shellQuoteTests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+		if strings.Contains(shellQuote(tt.in), "\n") {
+			t.Errorf("shellQuote(%q) spans more than one line", tt.in)
+		}
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to run the quoted words")
+	}
+	for _, tt := range shellQuoteTests {
+		script := "nl='\n'\nprintf '%s' " + shellQuote(tt.in)
+		out, err := exec.Command(sh, "-c", script).Output()
+		if err != nil {
+			t.Fatalf("sh failed for %q: %v", tt.in, err)
+		}
+		if string(out) != tt.in {
+			t.Errorf("sh read %q back as %q", tt.in, out)
+		}
+	}
+}
+
+func TestWriteScriptAbsolutePaths(t *testing.T) {
+	r := &Report{
+		Scan: Scan{Roots: []string{"."}, Algorithm: "md5"},
+		Groups: []Group{{
+			ID:   1,
+			Hash: "b1946ac92492d2347c6235b4d2611184",
+			Size: 6,
+			Files: []File{
+				{Path: "a", Role: RoleOriginal},
+				{Path: filepath.Join("sub", "b"), Role: RoleDuplicate},
+			},
+		}},
+	}
+	original, err := filepath.Abs("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicate, err := filepath.Abs(filepath.Join("sub", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		action ScriptAction
+		active bool
+		want   string
+	}{
+		{ScriptRemove, false, "# rm -- " + shellQuote(duplicate) + "  #"},
+		{ScriptRemove, true, "\nrm -- " + shellQuote(duplicate) + "  #"},
+		{ScriptLink, true, "\nln -f -- " + shellQuote(original) + " " + shellQuote(duplicate) + "  #"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := r.WriteScript(&buf, tt.action, tt.active); err != nil {
+			t.Fatal(err)
+		}
+		script := buf.String()
+		if !strings.Contains(script, tt.want) {
+			t.Errorf("%s script (active %v) lacks %q:\n%s", tt.action, tt.active, tt.want, script)
+		}
+		if !strings.Contains(script, "# Keep "+shellQuote(original)+"  #") {
+			t.Errorf("%s script keeps no absolute original:\n%s", tt.action, script)
+		}
+	}
+}
+
+func TestWriteScriptNeedsVerifiedOrCryptographicHash(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		verify    bool
+		refused   bool
+	}{
+		{"md5", false, false},
+		{"blake3", false, false},
+		{"xxh3", false, true},
+		{"xxhash64", false, true},
+		{"crc32c", false, true},
+		{"xxh3", true, false},
+		{"crc32c", true, false},
+	}
+	for _, tt := range tests {
+		r := &Report{Scan: Scan{Roots: []string{"."}, Algorithm: tt.algorithm, Verify: tt.verify}}
+		var buf bytes.Buffer
+		err := r.WriteScript(&buf, ScriptRemove, true)
+		if tt.refused {
+			if !errors.Is(err, ErrUnverifiedScript) {
+				t.Errorf("%s, verify %v: err = %v, want ErrUnverifiedScript", tt.algorithm, tt.verify, err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("%s, verify %v: refused script still wrote %q", tt.algorithm, tt.verify, buf.String())
+			}
+		} else if err != nil {
+			t.Errorf("%s, verify %v: %v", tt.algorithm, tt.verify, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	// Registers the pure Go "sqlite" driver, so no C compiler is needed
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteSchemaVersion is the version of the tables in schema.sql, stored in
+// the database's user_version
+const SQLiteSchemaVersion = 1
+
+// SQLiteSchema creates the tables, indexes and views of SQLite reports
+//
+//go:embed schema.sql
+var SQLiteSchema string
+
+// sqliteDriver is the database/sql driver used for SQLite reports
+const sqliteDriver = "sqlite"
+
+// WriteSQLite adds the report to the SQLite database at path, creating the
+// database if needed, and returns the ID of its row in the scans table. The
+// report is written in a single transaction, so an interrupted write leaves
+// earlier scans untouched. Paths are stored absolute, so scans run from
+// different directories can be compared.
+func (r *Report) WriteSQLite(path string) (int64, error) {
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	// SQLite only enforces foreign keys, and their cascades, on connections
+	// that ask for it, and the setting cannot change inside a transaction
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer tx.Rollback()
+
+	if err := migrateSQLite(tx); err != nil {
+		return 0, fmt.Errorf("failed to prepare %s: %w", path, err)
+	}
+	scanID, err := r.insertSQLite(tx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return scanID, nil
+}
+
+// migrateSQLite creates the tables of a new database and refuses databases
+// written by a newer release
+func migrateSQLite(tx *sql.Tx) error {
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > SQLiteSchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d", version, SQLiteSchemaVersion)
+	}
+
+	if _, err := tx.Exec(SQLiteSchema); err != nil {
+		return err
+	}
+	// PRAGMA does not take parameters
+	_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", SQLiteSchemaVersion))
+	return err
+}
+
+// insertSQLite inserts the report's scan, hashes, groups and files
+func (r *Report) insertSQLite(tx *sql.Tx) (int64, error) {
+	scan := r.Scan
+	scan.Roots = make([]string, len(r.Scan.Roots))
+	for i, root := range r.Scan.Roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return 0, err
+		}
+		scan.Roots[i] = abs
+	}
+	roots, err := json.Marshal(scan.Roots)
+	if err != nil {
+		return 0, err
+	}
+	parameters, err := json.Marshal(scan)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`INSERT INTO scans (tool, tool_version, schema_version, roots, algorithm,
+		original_rule, parameters, started_at, finished_at, incomplete, files_scanned, files_hashed,
+		duplicate_groups, duplicate_files, wasted_bytes, reclaimable_bytes, errors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Tool.Name, r.Tool.Version, r.SchemaVersion, string(roots), r.Scan.Algorithm,
+		r.Scan.OriginalRule, string(parameters), sqliteTime(r.Scan.StartedAt), sqliteTime(r.Scan.FinishedAt),
+		r.Scan.Incomplete, r.Summary.FilesScanned, r.Summary.FilesHashed, r.Summary.DuplicateGroups,
+		r.Summary.DuplicateFiles, r.Summary.WastedBytes, r.Summary.ReclaimableBytes, r.Summary.Errors)
+	if err != nil {
+		return 0, err
+	}
+	scanID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// Hashes already stored by an earlier scan are reused
+	insertHash, err := tx.Prepare(`INSERT INTO hashes (algorithm, digest, size) VALUES (?, ?, ?)
+		ON CONFLICT (algorithm, digest, size) DO UPDATE SET size = excluded.size RETURNING id`)
+	if err != nil {
+		return 0, err
+	}
+	defer insertHash.Close()
+	insertGroup, err := tx.Prepare(`INSERT INTO groups (scan_id, number, hash_id, wasted_bytes,
+		reclaimable_bytes, cross_root) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insertGroup.Close()
+	insertFile, err := tx.Prepare(`INSERT INTO files (scan_id, group_id, path, directory, name, root,
+		role, mod_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insertFile.Close()
+
+	for _, group := range r.Groups {
+		var hashID int64
+		if err := insertHash.QueryRow(r.Scan.Algorithm, group.Hash, group.Size).Scan(&hashID); err != nil {
+			return 0, err
+		}
+		result, err := insertGroup.Exec(scanID, group.ID, hashID, group.WastedBytes,
+			group.ReclaimableBytes, group.CrossRoot)
+		if err != nil {
+			return 0, err
+		}
+		groupID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, file := range group.Files {
+			path, err := filepath.Abs(file.Path)
+			if err != nil {
+				return 0, err
+			}
+			root, err := filepath.Abs(file.Root)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := insertFile.Exec(scanID, groupID, path, filepath.Dir(path),
+				filepath.Base(path), root, file.Role, sqliteTime(file.ModTime)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return scanID, nil
+}
+
+// sqliteTime formats t the way SQLite's date and time functions read it
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
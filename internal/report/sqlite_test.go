@@ -0,0 +1,134 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sqliteTestReport(root string, finishedAt time.Time) *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Tool:          Tool{Name: "test", Version: "0"},
+		Scan: Scan{
+			Roots:        []string{root},
+			Algorithm:    "md5",
+			OriginalRule: "path",
+			StartedAt:    finishedAt.Add(-time.Second),
+			FinishedAt:   finishedAt,
+		},
+		Summary: Summary{FilesScanned: 2, FilesHashed: 2, DuplicateGroups: 1, DuplicateFiles: 1, WastedBytes: 6},
+		Groups: []Group{{
+			ID:          1,
+			Hash:        "b1946ac92492d2347c6235b4d2611184",
+			Size:        6,
+			WastedBytes: 6,
+			Files: []File{
+				{Path: filepath.Join(root, "a"), Root: root, Role: RoleOriginal, ModTime: finishedAt},
+				{Path: filepath.Join(root, "sub", "b"), Root: root, Role: RoleDuplicate, ModTime: finishedAt},
+			},
+		}},
+	}
+}
+
+func TestWriteSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.sqlite")
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	first, err := sqliteTestReport("one", now).WriteSQLite(path)
+	if err != nil {
+		t.Fatalf("first scan: %v", err)
+	}
+	second, err := sqliteTestReport("two", now.Add(time.Hour)).WriteSQLite(path)
+	if err != nil {
+		t.Fatalf("second scan: %v", err)
+	}
+	if first == second {
+		t.Fatalf("both scans stored as %d", first)
+	}
+
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != SQLiteSchemaVersion {
+		t.Errorf("user_version = %d, want %d", version, SQLiteSchemaVersion)
+	}
+
+	counts := map[string]int{
+		"SELECT COUNT(*) FROM scans":  2,
+		"SELECT COUNT(*) FROM groups": 2,
+		"SELECT COUNT(*) FROM files":  4,
+		// Both scans found the same content
+		"SELECT COUNT(*) FROM hashes": 1,
+	}
+	for query, want := range counts {
+		var got int
+		if err := db.QueryRow(query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+
+	var original, duplicate string
+	var files int
+	var wasted int64
+	err = db.QueryRow(`SELECT original_directory, duplicate_directory, files, wasted_bytes
+		FROM directory_pairs WHERE scan_id = ?`, second).Scan(&original, &duplicate, &files, &wasted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paths are stored absolute whatever directory the scan ran from
+	two, err := filepath.Abs("two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original != two || duplicate != filepath.Join(two, "sub") || files != 1 || wasted != 6 {
+		t.Errorf("directory_pairs = %s, %s, %d, %d", original, duplicate, files, wasted)
+	}
+
+	// Deleting a scan deletes its groups and files, leaving no row behind
+	// that references a missing one
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "DELETE FROM scans WHERE id = ?", first); err != nil {
+		t.Fatal(err)
+	}
+	for query, want := range map[string]int{
+		"SELECT COUNT(*) FROM groups": 1,
+		"SELECT COUNT(*) FROM files":  2,
+	} {
+		var got int
+		if err := conn.QueryRowContext(ctx, query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("after deleting a scan, %s = %d, want %d", query, got, want)
+		}
+	}
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Error("foreign_key_check reports rows referencing missing ones")
+	}
+}
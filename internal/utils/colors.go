@@ -16,6 +16,12 @@ var (
 	Bold    = color.New(color.Bold).SprintFunc()
 )
 
+// LogToStderr sends the colorized messages to standard error, leaving
+// standard output free for results
+func LogToStderr() {
+	color.Output = color.Error
+}
+
 // Colorized output functions
 func LogError(message string) {
 	color.Red("❌ Error: %s", message)
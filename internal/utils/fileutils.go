@@ -34,6 +34,16 @@ func MassagePath(outputDir, filename string) string {
 	return filepath.Join(outputDir, filename)
 }
 
+// OutputPath is like MassagePath for an output file with extension ext,
+// such as ".csv"
+func OutputPath(outputDir, filename, ext string) string {
+	outputDir = strings.TrimSuffix(outputDir, "/")
+	if !strings.HasSuffix(filename, ext) {
+		filename += ext
+	}
+	return filepath.Join(outputDir, filename)
+}
+
 // EnsureDirExists creates a directory if it doesn't exist
 func EnsureDirExists(dirPath string) error {
 	return os.MkdirAll(dirPath, 0755)
@@ -61,6 +71,21 @@ func WriteJSONFile(data interface{}, filePath string) error {
 	return nil
 }
 
+// WriteOutputFile writes already encoded data to a file, creating its
+// directory if needed
+func WriteOutputFile(data []byte, filePath string) error {
+	dir := filepath.Dir(filePath)
+	if err := EnsureDirExists(dir); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
 // FormatFileSize formats a file size in bytes to a human-readable string
 func FormatFileSize(bytes int64) string {
 	const unit = 1024
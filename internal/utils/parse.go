@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseFileSize parses a human-readable size such as "512", "10K", "1.5MB"
+// or "2GiB" into bytes. Units are powers of 1024, matching FormatFileSize.
+func ParseFileSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", value[n-1]); exp >= 0 {
+			for i := 0; i <= exp; i++ {
+				multiplier *= 1024
+			}
+			value = value[:n-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || !(number >= 0) {
+		return 0, fmt.Errorf("invalid size %q: expected a number with an optional unit such as 10K or 1.5MB", s)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which no int64 can hold
+	size := number * float64(multiplier)
+	if size >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("invalid size %q: larger than the largest supported size, %s", s, FormatFileSize(math.MaxInt64))
+	}
+	return int64(size), nil
+}
+
+// dateLayouts are the absolute time formats accepted by ParseTimeBound
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTimeBound parses either an age relative to now, such as "90m", "12h",
+// "30d" or "2w", or an absolute date such as "2024-01-31" or an RFC 3339
+// timestamp. Dates without a zone are read in local time.
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	value := strings.TrimSpace(s)
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if age, err := parseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q: expected an age such as 12h, 30d or 2w, or a date such as 2024-01-31", s)
+}
+
+// parseAge extends time.ParseDuration with day (d) and week (w) units. Units
+// are case-insensitive, as in ParseFileSize.
+func parseAge(s string) (time.Duration, error) {
+	value := strings.ToLower(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || !(n >= 0) {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			// As in ParseFileSize, float64(math.MaxInt64) rounds up to 2^63
+			age := n * float64(unit)
+			if age >= float64(math.MaxInt64) {
+				return 0, fmt.Errorf("invalid age %q: longer than the longest supported age, %s", s, time.Duration(math.MaxInt64))
+			}
+			return time.Duration(age), nil
+		}
+	}
+
+	// ParseDuration rejects ages that overflow itself
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return age, nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFileSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{" 512 ", 512, false},
+		{"10K", 10 << 10, false},
+		{"10k", 10 << 10, false},
+		{"10KB", 10 << 10, false},
+		{"10KiB", 10 << 10, false},
+		{"1.5MB", 3 << 19, false},
+		{"2G", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{"1P", 1 << 50, false},
+		{"7E", 7 << 60, false},
+		{"100B", 100, false},
+		{"8E", 0, true},
+		{"9E", 0, true},
+		{"1e30", 0, true},
+		{"inf", 0, true},
+		{"NaN", 0, true},
+		{"-1", 0, true},
+		{"-1K", 0, true},
+		{"", 0, true},
+		{"K", 0, true},
+		{"ten", 0, true},
+		{"10X", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFileSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFileSize(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFileSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"90m", now.Add(-90 * time.Minute), false},
+		{"12h", now.Add(-12 * time.Hour), false},
+		{"30d", now.Add(-30 * 24 * time.Hour), false},
+		{"1.5d", now.Add(-36 * time.Hour), false},
+		{"2w", now.Add(-14 * 24 * time.Hour), false},
+		{" 2w ", now.Add(-14 * 24 * time.Hour), false},
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local), false},
+		{"2024-01-31 08:30:00", time.Date(2024, 1, 31, 8, 30, 0, 0, time.Local), false},
+		{"2024-01-31T08:30:00", time.Date(2024, 1, 31, 8, 30, 0, 0, time.Local), false},
+		{"2024-01-31T08:30:00Z", time.Date(2024, 1, 31, 8, 30, 0, 0, time.UTC), false},
+		{"2024-01-31T08:30:00+02:00", time.Date(2024, 1, 31, 6, 30, 0, 0, time.UTC), false},
+		{"7D", now.Add(-7 * 24 * time.Hour), false},
+		{"2W", now.Add(-14 * 24 * time.Hour), false},
+		{"12H", now.Add(-12 * time.Hour), false},
+		{"1H30M", now.Add(-90 * time.Minute), false},
+		{"15000w", now.Add(-15000 * 7 * 24 * time.Hour), false},
+		{"999999999999d", time.Time{}, true},
+		{"16000w", time.Time{}, true},
+		{"9999999999h", time.Time{}, true},
+		{"infd", time.Time{}, true},
+		{"NaNd", time.Time{}, true},
+		{"-1d", time.Time{}, true},
+		{"-5h", time.Time{}, true},
+		{"d", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTimeBound(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}